@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+	"github.com/leychan/yinsuda-music/pkg/export"
+)
+
+type command func(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error)
+
+// commands maps "group sub" (or a bare name) to its implementation.
+var commands = map[string]command{
+	"songs list":     songsList,
+	"songs info":     songsInfo,
+	"songs url":      songsUrl,
+	"search":         search,
+	"playlists list": listsList(export.KindSongList),
+	"playlists show": listsShow(export.KindSongList),
+	"rankings list":  listsList(export.KindRankingList),
+	"rankings show":  listsShow(export.KindRankingList),
+	"export":         exportLists,
+	"token":          token,
+}
+
+func dispatch(g *globals, args []string, stderr io.Writer) (*result, error) {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return nil, usagef("no command given")
+	}
+
+	name := args[0]
+	cmd, ok := commands[name]
+	if !ok && len(args) > 1 {
+		name = args[0] + " " + args[1]
+		cmd, ok = commands[name]
+	}
+	if !ok {
+		return nil, usagef("unknown command %q; run yinsuda -h for the list", strings.Join(args[:min(2, len(args))], " "))
+	}
+	rest := args[len(strings.Fields(name)):]
+
+	fs := flag.NewFlagSet("yinsuda "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	g.register(fs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return cmd(ctx, g, fs, rest)
+}
+
+// parse parses flags; a parse failure is a usage error.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	return nil
+}
+
+func artistNames(s client.Song) string {
+	names := make([]string, 0, len(s.ArtistList))
+	for _, a := range s.ArtistList {
+		names = append(names, a.ArtistName)
+	}
+	return strings.Join(names, "/")
+}
+
+func songTable(data interface{}, songs []client.Song) *result {
+	r := &result{data: data, columns: []string{"ID", "NAME", "ARTISTS", "ALBUM", "DURATION", "STATUS"}}
+	for _, s := range songs {
+		r.rows = append(r.rows, []string{
+			s.SongId, s.SongName, artistNames(s), s.Album.AlbumName,
+			strconv.Itoa(s.Duration), strconv.Itoa(s.Status),
+		})
+	}
+	return r
+}
+
+func songsList(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+	cursor := fs.String("cursor", "", "queryInfo cursor from a previous page")
+	limit := fs.Int("limit", 20, "songs per page")
+	all := fs.Bool("all", false, "follow the cursor to the end of the catalog")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	c, _, err := g.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &client.GetSongListResponse{}
+	req := &client.GetSongListRequest{QueryInfo: *cursor, Limit: *limit}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := c.GetSongListContext(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		resp.SongList = append(resp.SongList, page.SongList...)
+		resp.NextQueryInfo = page.NextQueryInfo
+		if !*all || page.NextQueryInfo == "END" || page.NextQueryInfo == "" {
+			break
+		}
+		req = &client.GetSongListRequest{QueryInfo: page.NextQueryInfo, Limit: *limit}
+	}
+
+	r := songTable(resp, resp.SongList)
+	if resp.NextQueryInfo != "" && resp.NextQueryInfo != "END" {
+		fmt.Fprintf(fs.Output(), "next cursor: %s\n", resp.NextQueryInfo)
+	}
+	return r, nil
+}
+
+func songsInfo(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, usagef("songs info: at least one song id is required")
+	}
+	c, _, err := g.newClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.GetSongInfoContext(ctx, fs.Args())
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.SongList) == 0 {
+		return nil, &notFoundError{what: "song " + strings.Join(fs.Args(), ",")}
+	}
+	return songTable(resp, resp.SongList), nil
+}
+
+func songsUrl(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+	identity := fs.String("identity", "", "identityId of the end user")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, usagef("songs url: exactly one song id is required")
+	}
+	c, _, err := g.newClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.GetSongUrlContext(ctx, &client.GetSongUrlRequest{SongId: fs.Arg(0), IdentityId: *identity})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.MediaList) == 0 {
+		return nil, &notFoundError{what: "media for song " + fs.Arg(0)}
+	}
+
+	r := &result{data: resp, columns: []string{"TYPE", "COMPLETE", "START", "END", "EXPIRE", "URL"}}
+	for _, m := range resp.MediaList {
+		r.rows = append(r.rows, []string{m.FileType, strconv.Itoa(m.Complete), m.StartSecond, m.EndSecond, m.Expire, m.Url})
+	}
+	return r, nil
+}
+
+func search(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+	searchType := fs.String("type", "full", "search type: full, song or artist")
+	status := fs.String("status", "any", "status filter: any, available or unavailable")
+	offset := fs.Int("offset", 0, "result offset")
+	limit := fs.Int("limit", 20, "result count")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, usagef("search: search text is required")
+	}
+
+	types := map[string]client.SearchType{
+		"full": client.SearchTypeFull, "song": client.SearchTypeSongName, "artist": client.SearchTypeArtistName,
+	}
+	statuses := map[string]client.StatusFilter{
+		"any": client.StatusAny, "available": client.StatusAvailable, "unavailable": client.StatusUnavailable,
+	}
+	t, ok := types[*searchType]
+	if !ok {
+		return nil, usagef("search: unknown -type %q", *searchType)
+	}
+	s, ok := statuses[*status]
+	if !ok {
+		return nil, usagef("search: unknown -status %q", *status)
+	}
+	req, err := client.NewSearchQuery(strings.Join(fs.Args(), " ")).Type(t).Status(s).Page(*offset, *limit).Build()
+	if err != nil {
+		return nil, &usageError{msg: "search: " + err.Error()}
+	}
+
+	c, _, err := g.newClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.SearchSongContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(fs.Output(), "total: %d\n", resp.Total)
+	return songTable(resp, resp.SongList), nil
+}
+
+func listsList(kind export.Kind) command {
+	return func(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+		offset := fs.Int("offset", 0, "list offset")
+		length := fs.Int("length", 20, "lists per page")
+		if err := parse(fs, args); err != nil {
+			return nil, err
+		}
+		c, _, err := g.newClient()
+		if err != nil {
+			return nil, err
+		}
+		page := c.QuerySongListPageContext
+		if kind == export.KindRankingList {
+			page = c.QueryRankingListPageContext
+		}
+		resp, err := page(ctx, &client.PageRequest{Offset: *offset, Length: *length})
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(fs.Output(), "total: %d\n", resp.Total)
+		r := &result{data: resp, columns: []string{"CODE", "TITLE", "STATUS", "DESCRIPTION"}}
+		for _, l := range resp.List {
+			r.rows = append(r.rows, []string{l.Code, l.Title, strconv.Itoa(l.Status), l.Description})
+		}
+		return r, nil
+	}
+}
+
+func listsShow(kind export.Kind) command {
+	return func(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+		if err := parse(fs, args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() != 1 {
+			return nil, usagef("%s show: exactly one code is required", kind)
+		}
+		c, _, err := g.newClient()
+		if err != nil {
+			return nil, err
+		}
+		detail := c.QuerySongListDetailContext
+		if kind == export.KindRankingList {
+			detail = c.QueryRankingListDetailContext
+		}
+		resp, err := detail(ctx, fs.Arg(0))
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code == "" && len(resp.SongList) == 0 {
+			return nil, &notFoundError{what: string(kind) + " " + fs.Arg(0)}
+		}
+
+		fmt.Fprintf(fs.Output(), "%s: %s (status %d)\n", resp.Code, resp.Title, resp.Status)
+		r := &result{data: resp, columns: []string{"POSITION", "SONG ID"}}
+		for i, s := range resp.SongList {
+			r.rows = append(r.rows, []string{strconv.Itoa(i + 1), s.SongId})
+		}
+		return r, nil
+	}
+}
+
+// exportLists writes files itself; its result is a summary of what was written.
+func exportLists(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+	kind := fs.String("kind", string(export.KindSongList), "what to export: playlists or rankings")
+	format := fs.String("format", "json", "file format: json, csv or m3u")
+	out := fs.String("file", "", "write everything to this file")
+	dir := fs.String("dir", "", "write one file per list into this directory")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return nil, &usageError{msg: err.Error()}
+	}
+	if (*out == "") == (*dir == "") {
+		return nil, usagef("export: exactly one of -file or -dir is required")
+	}
+	c, _, err := g.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := export.NewExporter(c).Collect(ctx, export.Kind(*kind))
+	if err != nil {
+		return nil, err
+	}
+	if *dir != "" {
+		err = export.WriteDir(*dir, f, lists)
+	} else {
+		var w *os.File
+		if w, err = os.Create(*out); err == nil {
+			err = export.Write(w, f, lists)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := &result{data: lists, columns: []string{"CODE", "TITLE", "SONGS"}}
+	for _, l := range lists {
+		r.rows = append(r.rows, []string{l.Code, l.Title, strconv.Itoa(len(l.Songs))})
+	}
+	return r, nil
+}
+
+func token(ctx context.Context, g *globals, fs *flag.FlagSet, args []string) (*result, error) {
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	c, _, err := g.newClient()
+	if err != nil {
+		return nil, err
+	}
+	tp := c.TokenProvider()
+	tok, err := tp.GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		AccessToken string    `json:"accessToken"`
+		ExpiresAt   time.Time `json:"expiresAt"`
+	}{tok, tp.ExpiresAt()}
+	return &result{
+		data:    data,
+		columns: []string{"ACCESS TOKEN", "EXPIRES AT"},
+		rows:    [][]string{{tok, data.ExpiresAt.Format(time.RFC3339)}},
+	}, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// profile holds the credentials for one Yinsuda app.
+type profile struct {
+	AppId     string
+	AppSecret string
+	Host      string
+}
+
+// getenv returns $YINSUDA_<NAME>. For the credential variables the
+// lowercase yinsuda_<name> spelling of earlier releases is still read as a
+// fallback.
+func getenv(name string) string {
+	if v := os.Getenv("YINSUDA_" + strings.ToUpper(name)); v != "" {
+		return v
+	}
+	switch name {
+	case "appid", "appsecret", "host":
+		return os.Getenv("yinsuda_" + name)
+	}
+	return ""
+}
+
+// defaultConfigPath is $YINSUDA_CONFIG, else ~/.config/yinsuda/config.
+func defaultConfigPath() string {
+	if p := getenv("config"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "yinsuda", "config")
+}
+
+// loadProfile reads the named profile from an INI-style config file:
+//
+//	[default]
+//	appid = 1009232
+//	appsecret = ...
+//	host = https://api.yinsuda.com
+//
+//	[staging]
+//	...
+//
+// A missing file is not an error. The environment variables YINSUDA_APPID,
+// YINSUDA_APPSECRET and YINSUDA_HOST override the file.
+func loadProfile(path, name string) (profile, error) {
+	var p profile
+	if path != "" {
+		sections, err := parseConfig(path)
+		if err != nil && !os.IsNotExist(err) {
+			return p, err
+		}
+		if err == nil {
+			s, ok := sections[name]
+			if !ok && name != "default" {
+				return p, fmt.Errorf("profile %q not found in %s", name, path)
+			}
+			p = profile{AppId: s["appid"], AppSecret: s["appsecret"], Host: s["host"]}
+		}
+	}
+
+	if v := getenv("appid"); v != "" {
+		p.AppId = v
+	}
+	if v := getenv("appsecret"); v != "" {
+		p.AppSecret = v
+	}
+	if v := getenv("host"); v != "" {
+		p.Host = v
+	}
+	p.Host = strings.TrimRight(p.Host, "/")
+
+	if p.AppId == "" || p.AppSecret == "" || p.Host == "" {
+		return p, fmt.Errorf("missing credentials: set appid, appsecret and host in profile %q of %s, "+
+			"or the YINSUDA_APPID, YINSUDA_APPSECRET and YINSUDA_HOST environment variables", name, path)
+	}
+	return p, nil
+}
+
+func parseConfig(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections := make(map[string]map[string]string)
+	current := "default"
+	sc := bufio.NewScanner(f)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, lineNo)
+		}
+		if sections[current] == nil {
+			sections[current] = make(map[string]string)
+		}
+		sections[current][strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return sections, sc.Err()
+}
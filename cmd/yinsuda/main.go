@@ -0,0 +1,160 @@
+// Command yinsuda is a command-line client for the Yinsuda Music API.
+//
+//	yinsuda [global flags] <command> [subcommand] [flags] [args]
+//
+// Credentials come from a profile in the config file (see -config, -profile)
+// or from the YINSUDA_APPID, YINSUDA_APPSECRET and YINSUDA_HOST environment variables.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Exit codes, one per error class so scripts can branch on them.
+const (
+	exitOK        = 0
+	exitError     = 1 // transport or other unclassified failure
+	exitUsage     = 2 // bad flags, arguments, configuration or request fields
+	exitAuth      = 3 // access token could not be obtained
+	exitAPI       = 4 // API answered with a business error code
+	exitHTTP      = 5 // API answered with a non-200 HTTP status or a non-JSON body
+	exitNotFound  = 6 // requested entity does not exist
+	exitInterrupt = 130
+)
+
+const usage = `Usage: yinsuda [global flags] <command> [flags] [args]
+
+Commands:
+  songs list      [-cursor C] [-limit N] [-all]    page through the catalog
+  songs info      ID...                            song details
+  songs url       [-identity ID] ID                media URLs for a song
+  search          [-type T] [-status S] [-offset N] [-limit N] TEXT
+  playlists list  [-offset N] [-length N]
+  playlists show  CODE
+  rankings list   [-offset N] [-length N]
+  rankings show   CODE
+  export          [-kind K] [-format F] (-file PATH | -dir DIR)
+  token                                            fetch an access token
+
+Global flags (also accepted after the command):
+  -o FORMAT     output format: table, json or yaml (default table)
+  -profile P    config profile (default $YINSUDA_PROFILE or "default")
+  -config PATH  config file (default $YINSUDA_CONFIG or ~/.config/yinsuda/config)
+
+Exit codes: 0 ok, 1 error, 2 usage, 3 auth, 4 API error, 5 HTTP error, 6 not found
+`
+
+// usageError marks errors caused by the invocation rather than the API.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// notFoundError is reported when the API succeeds but returns nothing for the requested id.
+type notFoundError struct{ what string }
+
+func (e *notFoundError) Error() string { return e.what + " not found" }
+
+// globals are the flags every command accepts.
+type globals struct {
+	output  string
+	profile string
+	config  string
+}
+
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.output, "o", g.output, "output format: table, json or yaml")
+	fs.StringVar(&g.profile, "profile", g.profile, "config profile")
+	fs.StringVar(&g.config, "config", g.config, "config file")
+}
+
+func (g *globals) newClient() (*client.Client, profile, error) {
+	switch g.output {
+	case "table", "json", "yaml":
+	default:
+		return nil, profile{}, usagef("unknown output format %q (want table, json or yaml)", g.output)
+	}
+	p, err := loadProfile(g.config, g.profile)
+	if err != nil {
+		return nil, p, &usageError{msg: err.Error()}
+	}
+	return client.NewClient(p.AppId, p.AppSecret, p.Host), p, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	g := &globals{output: "table", profile: getenv("profile"), config: defaultConfigPath()}
+	if g.profile == "" {
+		g.profile = "default"
+	}
+
+	fs := flag.NewFlagSet("yinsuda", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	g.register(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	res, err := dispatch(g, fs.Args(), stderr)
+	if err == nil {
+		err = printResult(stdout, g.output, res)
+		if err != nil {
+			err = &usageError{msg: err.Error()}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "yinsuda: %v\n", err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode maps an error to its exit code.
+func exitCode(err error) int {
+	var (
+		uErr    *usageError
+		nfErr   *notFoundError
+		authErr *client.AuthError
+		apiErr  *client.APIError
+		jsonErr *client.NonJSONError
+		valErr  *client.ValidationError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupt
+	case errors.As(err, &uErr), errors.As(err, &valErr):
+		return exitUsage
+	case errors.As(err, &nfErr):
+		return exitNotFound
+	case errors.As(err, &authErr):
+		return exitAuth
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode != 200 {
+			return exitHTTP
+		}
+		return exitAPI
+	case errors.As(err, &jsonErr):
+		return exitHTTP
+	}
+	return exitError
+}
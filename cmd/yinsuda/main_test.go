@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+func TestRun(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.TokenResponse{Code: "0", Success: true, Data: client.TokenData{AccessToken: "tok", Expire: 900}})
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/getSongInfo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.BaseResponse{Code: 0, Success: true, Data: client.GetSongInfoResponse{
+			SongList: []client.Song{{SongId: "S1", SongName: "晴天", Duration: 269, Status: 1,
+				ArtistList: []client.Artist{{ArtistName: "周杰伦"}}}},
+		}})
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/getSongUrl", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.BaseResponse{Code: 1001, Success: false, Message: "no license"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Setenv("YINSUDA_CONFIG", "")
+	t.Setenv("YINSUDA_APPID", "app")
+	t.Setenv("YINSUDA_APPSECRET", "secret")
+	t.Setenv("YINSUDA_HOST", server.URL)
+
+	cases := []struct {
+		args     []string
+		code     int
+		contains string
+	}{
+		{[]string{"songs", "info", "S1"}, exitOK, "周杰伦"},
+		{[]string{"-o", "yaml", "songs", "info", "S1"}, exitOK, "songList:\n  - songId: S1\n    songName: 晴天\n"},
+		{[]string{"songs", "info", "-o", "json", "S1"}, exitOK, `"songName": "晴天"`},
+		{[]string{"songs", "url", "S1"}, exitAPI, ""},
+		{[]string{"songs", "info"}, exitUsage, ""},
+		{[]string{"songs", "nope"}, exitUsage, ""},
+		{[]string{"search", "-type", "album", "x"}, exitUsage, ""},
+		{[]string{"-o", "xml", "token"}, exitUsage, ""},
+		{[]string{"token", "-o", "json"}, exitOK, `"accessToken": "tok"`},
+	}
+	for _, tc := range cases {
+		var stdout, stderr bytes.Buffer
+		code := run(tc.args, &stdout, &stderr)
+		if code != tc.code {
+			t.Errorf("%v: expected exit %d, got %d (stderr: %s)", tc.args, tc.code, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tc.contains) {
+			t.Errorf("%v: output missing %q:\n%s", tc.args, tc.contains, stdout.String())
+		}
+	}
+
+	// Commands stop when their context is cancelled, as on Ctrl-C.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g := &globals{output: "table", profile: "default"}
+	fs := flag.NewFlagSet("yinsuda songs info", flag.ContinueOnError)
+	g.register(fs)
+	if _, err := songsInfo(ctx, g, fs, []string{"S1"}); exitCode(err) != exitInterrupt {
+		t.Errorf("expected interrupt exit code for a cancelled command, got %v", err)
+	}
+
+	// An unreachable auth endpoint is an auth failure.
+	t.Setenv("YINSUDA_HOST", server.URL+"/nowhere")
+	if code := run([]string{"token"}, &bytes.Buffer{}, &bytes.Buffer{}); code != exitAuth {
+		t.Errorf("expected auth exit code, got %d", code)
+	}
+
+	// The lowercase credential variables are still read.
+	t.Setenv("YINSUDA_HOST", "")
+	t.Setenv("yinsuda_host", server.URL)
+	if code := run([]string{"token"}, &bytes.Buffer{}, &bytes.Buffer{}); code != exitOK {
+		t.Errorf("expected lowercase yinsuda_host to be read, got exit %d", code)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// result is what a command prints: data for json/yaml, rows for table.
+type result struct {
+	data    interface{}
+	columns []string
+	rows    [][]string
+}
+
+func printResult(w io.Writer, format string, r *result) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.data)
+	case "yaml":
+		return writeYAML(w, r.data)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(r.columns, "\t"))
+		for _, row := range r.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q (want json, table or yaml)", format)
+}
+
+// yamlNode is a JSON value with object key order preserved.
+type yamlNode struct {
+	keys   []string // object keys, in order; nil for non-objects
+	items  []*yamlNode
+	scalar interface{} // string, json.Number, bool or nil
+	kind   byte        // '{', '[' or 0 for scalars
+}
+
+// writeYAML renders v as block-style YAML by way of its JSON encoding, so the
+// struct tags that define the wire format also define the YAML field names.
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := readNode(dec)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if root.kind == 0 || len(root.items) == 0 {
+		buf.WriteString(yamlInline(root))
+		buf.WriteByte('\n')
+	} else {
+		emitYAML(&buf, root, 0)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func readNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return &yamlNode{scalar: tok}, nil
+	}
+
+	n := &yamlNode{kind: byte(delim)}
+	for dec.More() {
+		if n.kind == '{' {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, keyTok.(string))
+		}
+		child, err := readNode(dec)
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, child)
+	}
+	_, err = dec.Token() // closing delimiter
+	return n, err
+}
+
+func emitYAML(buf *bytes.Buffer, n *yamlNode, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for i, child := range n.items {
+		if n.kind == '{' {
+			buf.WriteString(pad + yamlString(n.keys[i]) + ":")
+		} else {
+			buf.WriteString(pad + "-")
+		}
+		switch {
+		case child.kind == 0 || len(child.items) == 0:
+			buf.WriteString(" " + yamlInline(child) + "\n")
+		case n.kind == '[' && child.kind == '{':
+			// "- key: value" with the remaining keys aligned under the first.
+			var inner bytes.Buffer
+			emitYAML(&inner, child, indent+1)
+			buf.WriteString(" " + strings.TrimPrefix(inner.String(), pad+"  "))
+		default:
+			buf.WriteString("\n")
+			emitYAML(buf, child, indent+1)
+		}
+	}
+}
+
+func yamlInline(n *yamlNode) string {
+	switch n.kind {
+	case '{':
+		return "{}"
+	case '[':
+		return "[]"
+	}
+	switch v := n.scalar.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	}
+	return fmt.Sprint(n.scalar)
+}
+
+var (
+	yamlPlain    = regexp.MustCompile(`^[\p{L}\p{N}_./][\p{L}\p{N}_./ ()-]*$`)
+	yamlReserved = regexp.MustCompile(`^(?i:true|false|yes|no|on|off|null|y|n|~)$|^[-+]?[0-9.]`)
+)
+
+// yamlString emits s plain when that is unambiguous, otherwise double-quoted.
+// JSON string syntax is a valid YAML double-quoted scalar.
+func yamlString(s string) string {
+	if yamlPlain.MatchString(s) && !yamlReserved.MatchString(s) && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
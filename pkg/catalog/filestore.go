@@ -0,0 +1,299 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+const (
+	songsFile      = "songs.jsonl"
+	checkpointFile = "checkpoint.json"
+)
+
+// FileStore is an embedded on-disk Store.
+//
+// Layout inside dir:
+//   - songs.jsonl: append-only log, one Song per line; the last line for a SongId wins.
+//   - checkpoint.json: replaced atomically (write temp file, fsync, rename).
+//
+// A crash can leave at most one partially written line at the end of songs.jsonl;
+// it is discarded when the store is reopened. A failed Put is cut off the log
+// before it returns. All songs are kept in memory once loaded.
+type FileStore struct {
+	dir string
+
+	lock   sync.RWMutex
+	log    logFile
+	songs  map[string]client.Song
+	lines  int   // number of records in the log, used to decide when to compact
+	broken error // set when a failed append could not be rolled back
+}
+
+// logFile is the part of *os.File the song log uses.
+type logFile interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// OpenFileStore opens (or creates) a FileStore in dir.
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store dir: %w", err)
+	}
+
+	s := &FileStore{
+		dir:   dir,
+		songs: make(map[string]client.Song),
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, songsFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open song log: %w", err)
+	}
+	if err := s.load(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.log = f
+	return s, nil
+}
+
+// load replays the log into memory and truncates a torn trailing record.
+func (s *FileStore) load(f *os.File) error {
+	r := bufio.NewReader(f)
+	var good int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// len(line) > 0 here means the last write never got its newline: drop it.
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read song log: %w", err)
+		}
+
+		var song client.Song
+		if err := json.Unmarshal(bytes.TrimSpace(line), &song); err != nil {
+			// A complete line that does not parse is corruption, not a torn write.
+			return fmt.Errorf("corrupt song log at offset %d: %w", good, err)
+		}
+		s.songs[song.SongId] = song
+		s.lines++
+		good += int64(len(line))
+	}
+
+	if err := f.Truncate(good); err != nil {
+		return fmt.Errorf("failed to truncate song log: %w", err)
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek song log: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Get(songId string) (client.Song, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	song, ok := s.songs[songId]
+	return song, ok, nil
+}
+
+// Put appends songs to the log and fsyncs before updating the in-memory view.
+func (s *FileStore) Put(songs ...client.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, song := range songs {
+		if err := enc.Encode(song); err != nil {
+			return fmt.Errorf("failed to encode song %s: %w", song.SongId, err)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.appendLocked(buf.Bytes()); err != nil {
+		return err
+	}
+	for _, song := range songs {
+		s.songs[song.SongId] = song
+	}
+	s.lines += len(songs)
+
+	// Rewrite the log once superseded records dominate it.
+	if s.lines > 1024 && s.lines > 2*len(s.songs) {
+		return s.compactLocked()
+	}
+	return nil
+}
+
+// appendLocked writes data to the end of the log and fsyncs it. If that
+// fails, the log is truncated back to where it was so a partial record does
+// not corrupt it; if even that fails, the store refuses further writes.
+func (s *FileStore) appendLocked(data []byte) error {
+	if s.broken != nil {
+		return fmt.Errorf("song log unusable after an earlier failure: %w", s.broken)
+	}
+	off, err := s.log.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek song log: %w", err)
+	}
+
+	_, err = s.log.Write(data)
+	if err == nil {
+		if err = s.log.Sync(); err != nil {
+			err = fmt.Errorf("failed to sync song log: %w", err)
+		}
+	} else {
+		err = fmt.Errorf("failed to append song log: %w", err)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if terr := s.log.Truncate(off); terr != nil {
+		s.broken = terr
+	} else if _, serr := s.log.Seek(off, io.SeekStart); serr != nil {
+		s.broken = serr
+	}
+	return err
+}
+
+func (s *FileStore) Range(fn func(client.Song) bool) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, song := range s.songs {
+		if !fn(song) {
+			break
+		}
+	}
+	return nil
+}
+
+// Compact rewrites the log so it holds exactly one record per song.
+func (s *FileStore) Compact() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.compactLocked()
+}
+
+func (s *FileStore) compactLocked() error {
+	path := filepath.Join(s.dir, songsFile)
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, song := range s.songs {
+			if err := enc.Encode(song); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compact song log: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen song log: %w", err)
+	}
+	s.log.Close()
+	s.log = f
+	s.lines = len(s.songs)
+	return nil
+}
+
+func (s *FileStore) Checkpoint() (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(filepath.Join(s.dir, checkpointFile))
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+func (s *FileStore) SaveCheckpoint(cp Checkpoint) error {
+	err := writeFileAtomic(filepath.Join(s.dir, checkpointFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(cp)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.log.Close()
+}
+
+// writeFileAtomic writes path via a temp file in the same directory so readers
+// (and a restart after a crash) see either the old or the new content, never a mix.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// The rename itself is only durable once the directory is synced.
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes dir's entries to disk. Windows neither supports nor needs it.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Checkpoint records how far the full load has progressed.
+// NextQueryInfo is the cursor returned by the last persisted GetSongList page.
+type Checkpoint struct {
+	NextQueryInfo string    `json:"nextQueryInfo"`
+	Complete      bool      `json:"complete"` // true once the cursor returned "END"
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Store persists the local catalog mirror.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the stored song, or ok=false if it is unknown.
+	Get(songId string) (song client.Song, ok bool, err error)
+	// Put inserts or replaces songs by SongId. Once Put returns, the songs must be durable.
+	Put(songs ...client.Song) error
+	// Range calls fn for every stored song until fn returns false.
+	Range(fn func(client.Song) bool) error
+	// Checkpoint returns the last saved checkpoint (zero value if none).
+	Checkpoint() (Checkpoint, error)
+	// SaveCheckpoint durably replaces the checkpoint.
+	SaveCheckpoint(cp Checkpoint) error
+	Close() error
+}
+
+// MemoryStore is a non-persistent Store, mainly useful for tests and short-lived tools.
+type MemoryStore struct {
+	lock  sync.RWMutex
+	songs map[string]client.Song
+	cp    Checkpoint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{songs: make(map[string]client.Song)}
+}
+
+func (s *MemoryStore) Get(songId string) (client.Song, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	song, ok := s.songs[songId]
+	return song, ok, nil
+}
+
+func (s *MemoryStore) Put(songs ...client.Song) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, song := range songs {
+		s.songs[song.SongId] = song
+	}
+	return nil
+}
+
+func (s *MemoryStore) Range(fn func(client.Song) bool) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, song := range s.songs {
+		if !fn(song) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Checkpoint() (Checkpoint, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.cp, nil
+}
+
+func (s *MemoryStore) SaveCheckpoint(cp Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cp = cp
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,220 @@
+// Package catalog keeps a local mirror of the licensed song catalog.
+//
+// The initial load walks GetSongList with its queryInfo cursor and persists the
+// cursor after every page, so an interrupted load resumes where it stopped.
+// Afterwards SONG notifications are applied incrementally via GetSongInfo.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// endCursor is the NextQueryInfo value signalling the last page.
+const endCursor = "END"
+
+// SongSource is the subset of *client.Client used by the Syncer.
+type SongSource interface {
+	GetSongListContext(ctx context.Context, req *client.GetSongListRequest) (*client.GetSongListResponse, error)
+	GetSongInfoContext(ctx context.Context, songIds []string) (*client.GetSongInfoResponse, error)
+}
+
+// Report summarises what a sync run changed in the store.
+type Report struct {
+	Pages   int
+	Added   []string // SongIds not previously stored
+	Updated []string // SongIds already stored (includes takedowns and restores)
+	// TakenDown lists songs whose Status went from available to
+	// unavailable, and songs first seen unavailable.
+	TakenDown []Takedown
+	// ReasonChanged lists songs that were and still are unavailable but
+	// whose TakeDownReason changed.
+	ReasonChanged []Takedown
+	Restored      []string // SongIds whose Status went from unavailable to available
+	Missing       []string // SongIds notified but not returned by GetSongInfo
+}
+
+// Takedown is an unavailable song and the reason the API gives for it.
+type Takedown struct {
+	SongId string
+	Reason string // the song's TakeDownReason
+}
+
+// Syncer mirrors the remote catalog into a Store.
+type Syncer struct {
+	src   SongSource
+	store Store
+
+	// PageSize is the GetSongList limit used for the full load. Defaults to 100.
+	PageSize int
+	// BatchSize caps the number of ids per GetSongInfo call. Defaults to 50.
+	BatchSize int
+	// OnTakedown, if set, is called for every song reported in
+	// Report.TakenDown.
+	OnTakedown func(song client.Song)
+}
+
+func NewSyncer(src SongSource, store Store) *Syncer {
+	return &Syncer{
+		src:       src,
+		store:     store,
+		PageSize:  100,
+		BatchSize: 50,
+	}
+}
+
+// FullSync loads the whole catalog, resuming from the stored checkpoint.
+// It returns immediately if a previous full load already completed; call Reset first to reload.
+func (s *Syncer) FullSync(ctx context.Context) (*Report, error) {
+	cp, err := s.store.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	for !cp.Complete {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		resp, err := s.src.GetSongListContext(ctx, &client.GetSongListRequest{
+			QueryInfo: cp.NextQueryInfo,
+			Limit:     s.PageSize,
+		})
+		if err != nil {
+			return report, fmt.Errorf("getSongList (queryInfo=%q) failed: %w", cp.NextQueryInfo, err)
+		}
+
+		// Songs first, cursor second: after a crash the page is fetched again
+		// and re-applied, which is harmless because Put is idempotent.
+		if err := s.apply(resp.SongList, report); err != nil {
+			return report, err
+		}
+		report.Pages++
+
+		cp = Checkpoint{
+			NextQueryInfo: resp.NextQueryInfo,
+			Complete:      resp.NextQueryInfo == endCursor || resp.NextQueryInfo == "",
+			UpdatedAt:     time.Now(),
+		}
+		if err := s.store.SaveCheckpoint(cp); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// Reset clears the checkpoint so the next FullSync walks the catalog from the start.
+// Stored songs are kept and simply overwritten.
+func (s *Syncer) Reset() error {
+	return s.store.SaveCheckpoint(Checkpoint{UpdatedAt: time.Now()})
+}
+
+// ApplyNotification refreshes the songs named in a SONG notification.
+// Other notification types are ignored.
+func (s *Syncer) ApplyNotification(ctx context.Context, n *client.Notification) (*Report, error) {
+	report := &Report{}
+	if n.NotifyType != client.NotifyTypeSong {
+		return report, nil
+	}
+
+	ids := make([]string, 0, len(n.Songs))
+	seen := make(map[string]bool, len(n.Songs))
+	for _, change := range n.Songs {
+		if change.SongId != "" && !seen[change.SongId] {
+			seen[change.SongId] = true
+			ids = append(ids, change.SongId)
+		}
+	}
+	return report, s.Refresh(ctx, ids, report)
+}
+
+// Refresh re-fetches the given songs via GetSongInfo and stores them.
+// report may be nil.
+func (s *Syncer) Refresh(ctx context.Context, songIds []string, report *Report) error {
+	if report == nil {
+		report = &Report{}
+	}
+
+	batch := s.BatchSize
+	if batch <= 0 {
+		batch = 50
+	}
+	for start := 0; start < len(songIds); start += batch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := start + batch
+		if end > len(songIds) {
+			end = len(songIds)
+		}
+		ids := songIds[start:end]
+
+		resp, err := s.src.GetSongInfoContext(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("getSongInfo failed: %w", err)
+		}
+		if err := s.apply(resp.SongList, report); err != nil {
+			return err
+		}
+
+		returned := make(map[string]bool, len(resp.SongList))
+		for _, song := range resp.SongList {
+			returned[song.SongId] = true
+		}
+		for _, id := range ids {
+			if !returned[id] {
+				report.Missing = append(report.Missing, id)
+			}
+		}
+	}
+	return nil
+}
+
+// apply classifies songs against the store, then persists them in one Put.
+func (s *Syncer) apply(songs []client.Song, report *Report) error {
+	if len(songs) == 0 {
+		return nil
+	}
+
+	var takenDown []client.Song
+	for _, song := range songs {
+		old, ok, err := s.store.Get(song.SongId)
+		if err != nil {
+			return err
+		}
+		down := Takedown{SongId: song.SongId, Reason: song.TakeDownReason}
+		if !ok {
+			report.Added = append(report.Added, song.SongId)
+			if song.Status == 0 {
+				report.TakenDown = append(report.TakenDown, down)
+				takenDown = append(takenDown, song)
+			}
+			continue
+		}
+		report.Updated = append(report.Updated, song.SongId)
+		switch {
+		case old.Status == 1 && song.Status == 0:
+			report.TakenDown = append(report.TakenDown, down)
+			takenDown = append(takenDown, song)
+		case old.Status == 0 && song.Status == 1:
+			report.Restored = append(report.Restored, song.SongId)
+		case old.Status == 0 && song.Status == 0 && old.TakeDownReason != song.TakeDownReason:
+			report.ReasonChanged = append(report.ReasonChanged, down)
+		}
+	}
+
+	if err := s.store.Put(songs...); err != nil {
+		return err
+	}
+
+	if s.OnTakedown != nil {
+		for _, song := range takenDown {
+			s.OnTakedown(song)
+		}
+	}
+	return nil
+}
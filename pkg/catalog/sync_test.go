@@ -0,0 +1,211 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// fakeSource serves a fixed catalog in pages, with the cursor being the next offset.
+type fakeSource struct {
+	songs  []client.Song
+	failAt string // GetSongList fails once when called with this cursor
+	calls  int
+}
+
+func (f *fakeSource) GetSongListContext(ctx context.Context, req *client.GetSongListRequest) (*client.GetSongListResponse, error) {
+	f.calls++
+	if f.failAt != "" && req.QueryInfo == f.failAt {
+		f.failAt = ""
+		return nil, errors.New("boom")
+	}
+	start, _ := strconv.Atoi(req.QueryInfo)
+	end := start + req.Limit
+	next := strconv.Itoa(end)
+	if end >= len(f.songs) {
+		end = len(f.songs)
+		next = "END"
+	}
+	return &client.GetSongListResponse{NextQueryInfo: next, SongList: f.songs[start:end]}, nil
+}
+
+func (f *fakeSource) GetSongInfoContext(ctx context.Context, songIds []string) (*client.GetSongInfoResponse, error) {
+	resp := &client.GetSongInfoResponse{}
+	for _, id := range songIds {
+		for _, s := range f.songs {
+			if s.SongId == id {
+				resp.SongList = append(resp.SongList, s)
+			}
+		}
+	}
+	return resp, nil
+}
+
+func TestSyncer_ResumeAndNotify(t *testing.T) {
+	src := &fakeSource{failAt: "4"}
+	for i := 0; i < 10; i++ {
+		src.songs = append(src.songs, client.Song{SongId: "S" + strconv.Itoa(i), Status: 1})
+	}
+
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("OpenFileStore failed: %v", err)
+	}
+
+	syncer := NewSyncer(src, store)
+	syncer.PageSize = 2
+
+	// First run dies on the third page.
+	if _, err := syncer.FullSync(context.Background()); err == nil {
+		t.Fatalf("expected first sync to fail")
+	}
+	store.Close()
+
+	// Simulate a torn write left behind by the crash.
+	f, _ := os.OpenFile(filepath.Join(dir, songsFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"songId":"S9","sta`)
+	f.Close()
+
+	store, err = OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer store.Close()
+
+	syncer = NewSyncer(src, store)
+	syncer.PageSize = 2
+	report, err := syncer.FullSync(context.Background())
+	if err != nil {
+		t.Fatalf("resumed sync failed: %v", err)
+	}
+	if report.Pages != 3 || len(report.Added) != 6 {
+		t.Errorf("expected resume from page 3 (3 pages, 6 added), got %d pages, %d added", report.Pages, len(report.Added))
+	}
+	cp, _ := store.Checkpoint()
+	if !cp.Complete {
+		t.Errorf("checkpoint not complete: %+v", cp)
+	}
+
+	// Take down one song and notify.
+	src.songs[3].Status = 0
+	src.songs[3].TakeDownReason = "license expired"
+	var notified []string
+	syncer.OnTakedown = func(s client.Song) { notified = append(notified, s.SongId) }
+
+	report, err = syncer.ApplyNotification(context.Background(), &client.Notification{
+		NotifyType: client.NotifyTypeSong,
+		Songs:      []client.SongChange{{SongId: "S3"}, {SongId: "GONE"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyNotification failed: %v", err)
+	}
+	if len(report.TakenDown) != 1 || report.TakenDown[0] != (Takedown{"S3", "license expired"}) || len(notified) != 1 || notified[0] != "S3" {
+		t.Errorf("expected S3 taken down, got report %+v, hook %v", report, notified)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != "GONE" {
+		t.Errorf("expected GONE missing, got %v", report.Missing)
+	}
+
+	got, ok, _ := store.Get("S3")
+	if !ok || got.Status != 0 || got.TakeDownReason != "license expired" {
+		t.Errorf("stored S3 not updated: %+v", got)
+	}
+}
+
+func TestSyncer_Takedowns(t *testing.T) {
+	src := &fakeSource{songs: []client.Song{
+		{SongId: "S0", Status: 1},
+		{SongId: "S1", Status: 0, TakeDownReason: "pending license"},
+	}}
+	store, err := OpenFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenFileStore failed: %v", err)
+	}
+	defer store.Close()
+
+	var notified []string
+	syncer := NewSyncer(src, store)
+	syncer.OnTakedown = func(s client.Song) { notified = append(notified, s.SongId) }
+
+	// Songs first seen unavailable are takedowns too.
+	report, err := syncer.FullSync(context.Background())
+	if err != nil {
+		t.Fatalf("FullSync failed: %v", err)
+	}
+	if len(report.TakenDown) != 1 || report.TakenDown[0] != (Takedown{"S1", "pending license"}) || len(notified) != 1 {
+		t.Errorf("expected S1 taken down on first sight, got %+v, hook %v", report.TakenDown, notified)
+	}
+
+	// A new reason for a song that stays down is reported separately.
+	src.songs[1].TakeDownReason = "license expired"
+	report, err = syncer.ApplyNotification(context.Background(), &client.Notification{
+		NotifyType: client.NotifyTypeSong,
+		Songs:      []client.SongChange{{SongId: "S0"}, {SongId: "S1"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyNotification failed: %v", err)
+	}
+	if len(report.TakenDown) != 0 || len(report.ReasonChanged) != 1 || report.ReasonChanged[0] != (Takedown{"S1", "license expired"}) {
+		t.Errorf("expected S1 reason change only, got %+v", report)
+	}
+	if len(notified) != 1 {
+		t.Errorf("reason change fired OnTakedown: %v", notified)
+	}
+}
+
+// tornLog writes half of the next record and then fails, like a full disk.
+type tornLog struct {
+	*os.File
+	fail bool
+}
+
+func (l *tornLog) Write(p []byte) (int, error) {
+	if !l.fail {
+		return l.File.Write(p)
+	}
+	l.fail = false
+	n, _ := l.File.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestFileStore_FailedPut(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("OpenFileStore failed: %v", err)
+	}
+	log := &tornLog{File: store.log.(*os.File)}
+	store.log = log
+
+	if err := store.Put(client.Song{SongId: "S1"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	log.fail = true
+	if err := store.Put(client.Song{SongId: "S2", SongName: "torn"}); err == nil {
+		t.Fatal("Expected the torn Put to fail")
+	}
+	if _, ok, _ := store.Get("S2"); ok {
+		t.Error("failed Put updated the in-memory view")
+	}
+	if err := store.Put(client.Song{SongId: "S3"}); err != nil {
+		t.Fatalf("Put after a failed Put failed: %v", err)
+	}
+	store.Close()
+
+	store, err = OpenFileStore(dir)
+	if err != nil {
+		t.Fatalf("reopening after a failed Put failed: %v", err)
+	}
+	defer store.Close()
+	for id, want := range map[string]bool{"S1": true, "S2": false, "S3": true} {
+		if _, ok, _ := store.Get(id); ok != want {
+			t.Errorf("%s stored = %v, want %v", id, ok, want)
+		}
+	}
+}
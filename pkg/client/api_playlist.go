@@ -1,5 +1,7 @@
 package client
 
+import "context"
+
 // --- Models ---
 
 type PlayListInfo struct {
@@ -45,55 +47,47 @@ type QuerySongListDetailResponse PlayListDetail
 // 5. QuerySongListPage
 
 func (c *Client) QuerySongListPage(req *PageRequest) (*QuerySongListResponse, error) {
-	var result struct {
-		Data QuerySongListResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/querySongListPage", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return c.QuerySongListPageContext(context.Background(), req)
+}
+
+// QuerySongListPageContext is QuerySongListPage with a context.
+func (c *Client) QuerySongListPageContext(ctx context.Context, req *PageRequest) (*QuerySongListResponse, error) {
+	return Call[*PageRequest, QuerySongListResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/querySongListPage", req)
 }
 
 // 6. QuerySongListDetail
 
 func (c *Client) QuerySongListDetail(code string) (*QuerySongListDetailResponse, error) {
+	return c.QuerySongListDetailContext(context.Background(), code)
+}
+
+// QuerySongListDetailContext is QuerySongListDetail with a context.
+func (c *Client) QuerySongListDetailContext(ctx context.Context, code string) (*QuerySongListDetailResponse, error) {
 	req := &QuerySongListDetailRequest{Code: code}
-	var result struct {
-		Data QuerySongListDetailResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/querySongListDetail", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return Call[*QuerySongListDetailRequest, QuerySongListDetailResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/querySongListDetail", req)
 }
 
 // 7. QueryRankingListPage
 // Logic is identical to QuerySongListPage
 
 func (c *Client) QueryRankingListPage(req *PageRequest) (*QuerySongListResponse, error) {
-	var result struct {
-		Data QuerySongListResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/queryRankingListPage", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return c.QueryRankingListPageContext(context.Background(), req)
+}
+
+// QueryRankingListPageContext is QueryRankingListPage with a context.
+func (c *Client) QueryRankingListPageContext(ctx context.Context, req *PageRequest) (*QuerySongListResponse, error) {
+	return Call[*PageRequest, QuerySongListResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/queryRankingListPage", req)
 }
 
 // 8. QueryRankingListDetail
 // Logic is identical to QuerySongListDetail
 
 func (c *Client) QueryRankingListDetail(code string) (*QuerySongListDetailResponse, error) {
+	return c.QueryRankingListDetailContext(context.Background(), code)
+}
+
+// QueryRankingListDetailContext is QueryRankingListDetail with a context.
+func (c *Client) QueryRankingListDetailContext(ctx context.Context, code string) (*QuerySongListDetailResponse, error) {
 	req := &QuerySongListDetailRequest{Code: code}
-	var result struct {
-		Data QuerySongListDetailResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/queryRankingListDetail", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return Call[*QuerySongListDetailRequest, QuerySongListDetailResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/queryRankingListDetail", req)
 }
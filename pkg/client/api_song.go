@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"strings"
 )
 
@@ -103,11 +105,13 @@ type GetSongUrlResponse struct {
 // 4. SearchSong
 
 type SearchSongRequest struct {
-	SearchText string `json:"searchText"`
-	SearchType int    `json:"searchType"` // 1: Full, 2: SongName, 3: ArtistName
-	Status     int    `json:"status,omitempty"`
-	Offset     int    `json:"offset"`
-	Limit      int    `json:"limit"`
+	SearchText string     `json:"searchText"`
+	SearchType SearchType `json:"searchType"` // 1: Full, 2: SongName, 3: ArtistName
+	Status     int        `json:"status,omitempty"`  // Use SetStatus to send 0; see SearchQuery
+	Offset     int        `json:"offset"`
+	Limit      int        `json:"limit"`
+
+	statusSet bool
 }
 
 type SearchSongResponse struct {
@@ -117,52 +121,69 @@ type SearchSongResponse struct {
 
 // --- Methods ---
 
+// GetSongList returns one page of the catalog. A Limit of 0 is sent as the
+// SongListLimit of the client's RequestDefaults; req itself is not modified.
 func (c *Client) GetSongList(req *GetSongListRequest) (*GetSongListResponse, error) {
-	if req.Limit == 0 {
-		req.Limit = 100
-	}
-	var result struct {
-		Data GetSongListResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/getSongList", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return c.GetSongListContext(context.Background(), req)
+}
+
+// GetSongListContext is GetSongList with a context, which bounds the limiter
+// wait as well as the request.
+func (c *Client) GetSongListContext(ctx context.Context, req *GetSongListRequest) (*GetSongListResponse, error) {
+	return Call[*GetSongListRequest, GetSongListResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/getSongList", c.defaults.songList(req))
 }
 
 func (c *Client) GetSongInfo(songIds []string) (*GetSongInfoResponse, error) {
+	return c.GetSongInfoContext(context.Background(), songIds)
+}
+
+// GetSongInfoContext is GetSongInfo with a context.
+func (c *Client) GetSongInfoContext(ctx context.Context, songIds []string) (*GetSongInfoResponse, error) {
 	req := &GetSongInfoRequest{
 		SongIdListStr: strings.Join(songIds, ","),
 	}
-	var result struct {
-		Data GetSongInfoResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/getSongInfo", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return Call[*GetSongInfoRequest, GetSongInfoResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/getSongInfo", req)
 }
 
+// GetSongUrl returns the media for a song. With WithMediaCache, successful
+// responses are cached and served instead of a *BreakerOpenError while the
+// circuit for this path is open. A MemoryMediaCache stops serving a response
+// once the Expire of any of its URLs has passed.
 func (c *Client) GetSongUrl(req *GetSongUrlRequest) (*GetSongUrlResponse, error) {
-	var result struct {
-		Data GetSongUrlResponse `json:"data"`
+	return c.GetSongUrlContext(context.Background(), req)
+}
+
+// GetSongUrlContext is GetSongUrl with a context. A nil req is treated as
+// the zero request, which fails validation.
+func (c *Client) GetSongUrlContext(ctx context.Context, req *GetSongUrlRequest) (*GetSongUrlResponse, error) {
+	if req == nil {
+		req = &GetSongUrlRequest{}
 	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/getSongUrl", nil, req, &result)
+	resp, err := Call[*GetSongUrlRequest, GetSongUrlResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/getSongUrl", req)
 	if err != nil {
+		// Serve the last known media while the circuit is open.
+		if c.mediaCache != nil && errors.Is(err, ErrBreakerOpen) {
+			cached, ok := c.mediaCache.Get(req.SongId, req.IdentityId)
+			c.metrics.CacheLookup(ctx, "media", ok)
+			if ok {
+				return cached, nil
+			}
+		}
 		return nil, err
 	}
-	return &result.Data, nil
+	if c.mediaCache != nil {
+		c.mediaCache.Put(req.SongId, req.IdentityId, resp)
+	}
+	return resp, nil
 }
 
+// SearchSong searches the catalog. A zero Limit or SearchType is replaced by
+// the client's RequestDefaults, if they set one; req itself is not modified.
 func (c *Client) SearchSong(req *SearchSongRequest) (*SearchSongResponse, error) {
-	var result struct {
-		Data SearchSongResponse `json:"data"`
-	}
-	err := c.Do("POST", "/mcrc-sas/yinsuda/searchSong", nil, req, &result)
-	if err != nil {
-		return nil, err
-	}
-	return &result.Data, nil
+	return c.SearchSongContext(context.Background(), req)
+}
+
+// SearchSongContext is SearchSong with a context.
+func (c *Client) SearchSongContext(ctx context.Context, req *SearchSongRequest) (*SearchSongResponse, error) {
+	return Call[*SearchSongRequest, SearchSongResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/searchSong", c.defaults.search(req))
 }
@@ -2,8 +2,10 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -15,6 +17,9 @@ type TokenProvider struct {
 	appSecret  string
 	authUrl    string // e.g., "https://api.yinsuda.com/oauth2/token"
 	httpClient *http.Client
+	logger     *slog.Logger
+	tracer     Tracer
+	metrics    Metrics
 
 	lock        sync.RWMutex
 	accessToken string
@@ -30,11 +35,19 @@ func NewTokenProvider(appId, appSecret, authUrl string, client *http.Client) *To
 		appSecret:  appSecret,
 		authUrl:    authUrl,
 		httpClient: client,
+		tracer:     noopTracer{},
+		metrics:    noopMetrics{},
 	}
 }
 
 // GetAccessToken returns a valid access token, refreshing if necessary.
 func (p *TokenProvider) GetAccessToken() (string, error) {
+	return p.GetAccessTokenContext(context.Background())
+}
+
+// GetAccessTokenContext is GetAccessToken with a context, which bounds the
+// token request and parents its span.
+func (p *TokenProvider) GetAccessTokenContext(ctx context.Context) (string, error) {
 	p.lock.RLock()
 	token := p.accessToken
 	expiry := p.expiresAt
@@ -53,17 +66,87 @@ func (p *TokenProvider) GetAccessToken() (string, error) {
 		return p.accessToken, nil
 	}
 
-	newToken, expireSeconds, err := p.fetchToken()
+	ctx, span := p.tracer.Start(ctx, "yinsuda token", Attribute{AttrAppId, p.appId})
+	defer span.End()
+	start := time.Now()
+	newToken, expireSeconds, err := p.fetchToken(ctx)
 	if err != nil {
-		return "", err
+		authErr, ok := err.(*AuthError)
+		if !ok {
+			authErr = &AuthError{Err: err}
+		}
+		span.RecordError(authErr)
+		p.metrics.TokenRefreshed(ctx, p.appId, time.Since(start), authErr)
+		if p.logger != nil {
+			p.logger.LogAttrs(ctx, slog.LevelError, "yinsuda token refresh failed",
+				slog.String("appId", p.appId),
+				slog.Int("status", authErr.StatusCode),
+				slog.String("code", authErr.Code),
+				slog.Duration("latency", time.Since(start)),
+				errorAttr(authErr))
+		}
+		return "", authErr
 	}
 
 	p.accessToken = newToken
 	p.expiresAt = time.Now().Add(time.Duration(expireSeconds) * time.Second)
+	p.metrics.TokenRefreshed(ctx, p.appId, time.Since(start), nil)
+	if p.logger != nil {
+		p.logger.LogAttrs(ctx, slog.LevelInfo, "yinsuda token refreshed",
+			slog.String("appId", p.appId),
+			slog.Int("expireSeconds", expireSeconds),
+			slog.Time("expiresAt", p.expiresAt),
+			slog.Duration("latency", time.Since(start)))
+	}
 	return newToken, nil
 }
 
-func (p *TokenProvider) fetchToken() (string, int, error) {
+// SetLogger makes the provider log each token refresh, including the expiry
+// it received, and each failed refresh. Tokens and the secret are never logged.
+func (p *TokenProvider) SetLogger(l *slog.Logger) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.logger = l
+}
+
+// SetTelemetry makes the provider trace each token fetch as its own span and
+// report it to m. nil arguments restore the no-op defaults; use SetTracer or
+// SetMetrics to change only one of them.
+func (p *TokenProvider) SetTelemetry(t Tracer, m Metrics) {
+	p.SetTracer(t)
+	p.SetMetrics(m)
+}
+
+// SetTracer makes the provider trace each token fetch as its own span.
+// nil restores the no-op default.
+func (p *TokenProvider) SetTracer(t Tracer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if t == nil {
+		t = noopTracer{}
+	}
+	p.tracer = t
+}
+
+// SetMetrics makes the provider report each token fetch to m. nil restores
+// the no-op default.
+func (p *TokenProvider) SetMetrics(m Metrics) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if m == nil {
+		m = noopMetrics{}
+	}
+	p.metrics = m
+}
+
+// ExpiresAt returns the expiry of the cached token; zero if none has been fetched.
+func (p *TokenProvider) ExpiresAt() time.Time {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.expiresAt
+}
+
+func (p *TokenProvider) fetchToken(ctx context.Context) (string, int, error) {
 	reqBody := map[string]string{
 		"appId":     p.appId,
 		"appSecret": p.appSecret,
@@ -73,7 +156,7 @@ func (p *TokenProvider) fetchToken() (string, int, error) {
 		return "", 0, err
 	}
 
-	req, err := http.NewRequest("POST", p.authUrl, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.authUrl, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return "", 0, err
 	}
@@ -86,7 +169,10 @@ func (p *TokenProvider) fetchToken() (string, int, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", 0, fmt.Errorf("auth request failed with status: %d", resp.StatusCode)
+		return "", 0, &AuthError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("auth request failed with status: %d", resp.StatusCode),
+		}
 	}
 
 	var tokenResp TokenResponse
@@ -95,7 +181,11 @@ func (p *TokenProvider) fetchToken() (string, int, error) {
 	}
 
 	if !tokenResp.Success || tokenResp.Code != "0" {
-		return "", 0, fmt.Errorf("auth failed: %s (%s)", tokenResp.Message, tokenResp.Msg)
+		return "", 0, &AuthError{
+			StatusCode: resp.StatusCode,
+			Code:       tokenResp.Code,
+			Err:        fmt.Errorf("auth failed: %s (%s)", tokenResp.Message, tokenResp.Msg),
+		}
 	}
 
 	return tokenResp.Data.AccessToken, tokenResp.Data.Expire, nil
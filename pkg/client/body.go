@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+)
+
+// Response size defaults; see WithMaxResponseSize and WithMaxErrorBody.
+const (
+	DefaultMaxResponseSize = 32 << 20
+	DefaultMaxErrorBody    = 4 << 10
+)
+
+// limitedBody reads at most limit bytes of a response and fails with a
+// *ResponseTooLargeError beyond that. limit <= 0 means no limit.
+type limitedBody struct {
+	r      io.Reader
+	left   int64
+	limit  int64
+	status int
+}
+
+func newLimitedBody(r io.Reader, limit int64, status int) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitedBody{r: r, left: limit, limit: limit, status: status}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		// Probe for one more byte to tell "exactly limit" from "over".
+		var one [1]byte
+		if n, _ := l.r.Read(one[:]); n > 0 {
+			return 0, &ResponseTooLargeError{StatusCode: l.status, Limit: l.limit}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	return n, err
+}
+
+// capBody returns at most max bytes of b and whether it was cut. max <= 0
+// keeps b whole.
+func capBody(b []byte, max int) ([]byte, bool) {
+	if max > 0 && len(b) > max {
+		return b[:max], true
+	}
+	return b, false
+}
+
+// readCapped reads up to max bytes of r for an error report, discarding the
+// rest unread. max <= 0 reads everything.
+func readCapped(r io.Reader, max int) ([]byte, bool) {
+	if max <= 0 {
+		b, _ := io.ReadAll(r)
+		return b, false
+	}
+	b, _ := io.ReadAll(io.LimitReader(r, int64(max)+1))
+	return capBody(b, max)
+}
+
+// peekJSON reports whether br starts, after whitespace, with a JSON object.
+// Nothing is consumed.
+func peekJSON(br *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil || len(b) < i {
+			return false
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			return true
+		}
+		return false
+	}
+}
+
+func (c *Client) nonJSONError(resp *http.Response, r io.Reader) error {
+	body, truncated := readCapped(r, c.maxErrorBody)
+	return &NonJSONError{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+		Truncated:   truncated,
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_ResponseLimits(t *testing.T) {
+	html := "<html><body>" + strings.Repeat("502 Bad Gateway ", 1000) + "</body></html>"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	mux.HandleFunc("/gateway", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(html))
+	})
+	mux.HandleFunc("/proxy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("\n  " + html))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"success":true,"data":{"songList":[` + strings.Repeat(`{"songId":"1"},`, 1000) + `{"songId":"1"}]}}`))
+	})
+	mux.HandleFunc("/rejected", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":1001,"success":false,"message":"bad","data":"` + strings.Repeat("x", 500) + `"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := NewClient("testAppId", "testAppSecret", server.URL, WithMaxResponseSize(4096), WithMaxErrorBody(64))
+	var result BaseResponse
+
+	// Non-200: the body is captured up to the limit.
+	var apiErr *APIError
+	err := c.Do("POST", "/gateway", nil, nil, &result)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 502 || len(apiErr.Body) != 64 || !apiErr.Truncated {
+		t.Fatalf("gateway: %v", err)
+	}
+	if !strings.HasSuffix(err.Error(), "...(truncated)") || len(err.Error()) > 200 {
+		t.Errorf("error message not truncated: %q", err.Error())
+	}
+
+	// 200 with HTML: typed error, not a parse failure.
+	var jsonErr *NonJSONError
+	err = c.Do("POST", "/proxy", nil, nil, &result)
+	if !errors.As(err, &jsonErr) || jsonErr.StatusCode != 200 || jsonErr.ContentType != "text/html" || !jsonErr.Truncated || len(jsonErr.Body) != 64 {
+		t.Fatalf("proxy: %v", err)
+	}
+	_, err = c.StreamSongList(context.Background(), &GetSongListRequest{}, func(Song) error { return nil })
+	if err == nil {
+		t.Fatal("Expected error from stream")
+	}
+
+	// Oversized success bodies fail with a typed error, buffered or streamed.
+	var tooLarge *ResponseTooLargeError
+	err = c.Do("POST", "/big", nil, nil, &result)
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 4096 {
+		t.Fatalf("big: %v", err)
+	}
+	mux.HandleFunc("/mcrc-sas/yinsuda/getSongList", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"success":true,"data":{"songList":[` + strings.Repeat(`{"songId":"1"},`, 1000) + `{"songId":"1"}]}}`))
+	})
+	// Streaming is not held to the buffered limit, only to its own.
+	n := 0
+	if _, err = c.StreamSongList(context.Background(), &GetSongListRequest{}, func(Song) error { n++; return nil }); err != nil || n != 1001 {
+		t.Fatalf("streamed big: %d songs, %v", n, err)
+	}
+	capped := NewClient("testAppId", "testAppSecret", server.URL, WithMaxStreamSize(4096))
+	n = 0
+	_, err = capped.StreamSongList(context.Background(), &GetSongListRequest{}, func(Song) error { n++; return nil })
+	if !errors.As(err, &tooLarge) || n == 0 || n > 4096/len(`{"songId":"1"},`) {
+		t.Fatalf("streamed big with limit: %d songs, %v", n, err)
+	}
+
+	// Business errors keep the envelope fields but cap the body.
+	err = c.Do("POST", "/rejected", nil, nil, &result)
+	if !errors.As(err, &apiErr) || apiErr.Code != 1001 || apiErr.Message != "bad" || len(apiErr.Body) != 64 || !apiErr.Truncated {
+		t.Fatalf("rejected: %v", err)
+	}
+
+	// No limits.
+	unlimited := NewClient("testAppId", "testAppSecret", server.URL, WithMaxResponseSize(0), WithMaxErrorBody(0))
+	if err := unlimited.Do("POST", "/big", nil, nil, &result); err != nil {
+		t.Fatalf("unlimited big: %v", err)
+	}
+	if err := unlimited.Do("POST", "/gateway", nil, nil, &result); !errors.As(err, &apiErr) || apiErr.Body != html || apiErr.Truncated {
+		t.Fatalf("unlimited gateway: %v", err)
+	}
+}
+
+func TestLimitedBody_Exact(t *testing.T) {
+	buf := make([]byte, 16)
+	r := newLimitedBody(strings.NewReader("0123456789"), 10, 200)
+	var got []byte
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil {
+			if err.Error() != "EOF" {
+				t.Fatalf("body of exactly the limit rejected: %v", err)
+			}
+			break
+		}
+	}
+	if string(got) != "0123456789" {
+		t.Errorf("got %q", got)
+	}
+}
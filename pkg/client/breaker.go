@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of one circuit.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// ErrBreakerOpen is matched by errors.Is for every *BreakerOpenError.
+var ErrBreakerOpen = errors.New("circuit breaker open")
+
+// BreakerOpenError is returned without sending the request while the
+// circuit for Path is open, or half-open with all probes in flight.
+type BreakerOpenError struct {
+	Path    string
+	State   BreakerState
+	RetryAt time.Time // when the circuit next admits a probe
+}
+
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s for %s, retry at %s", e.State, e.Path, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrBreakerOpen
+}
+
+// BreakerConfig configures a Breaker. Zero fields take the defaults noted.
+type BreakerConfig struct {
+	// ConsecutiveFailures trips the circuit after this many failures in a
+	// row. Defaults to 5; negative disables this trigger.
+	ConsecutiveFailures int
+	// FailureRate trips the circuit when the share of failures within
+	// Window reaches it, once at least MinRequests were made. 0 disables it.
+	FailureRate float64
+	MinRequests int           // defaults to 20
+	Window      time.Duration // defaults to 1 minute
+	// OpenTimeout is how long the circuit stays open before half-opening.
+	// Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is how many requests a half-open circuit lets through;
+	// if all succeed it closes, any failure reopens it. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure classifies a request's outcome. Defaults to
+	// DefaultBreakerFailure.
+	IsFailure func(err error) bool
+	// OnStateChange is called, outside the breaker's lock, on every transition.
+	OnStateChange func(path string, from, to BreakerState)
+}
+
+// DefaultBreakerFailure counts transport errors, timeouts, unparseable
+// responses and HTTP 5xx as failures. Business errors and other 4xx show the
+// gateway is answering and do not count; neither does the caller cancelling
+// or a streaming callback stopping the stream.
+func DefaultBreakerFailure(err error) bool {
+	var cbErr *callbackError
+	if err == nil || errors.Is(err, context.Canceled) || errors.As(err, &cbErr) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr.StatusCode == 0 || authErr.StatusCode >= 500
+	}
+	return true
+}
+
+// Breaker keeps one circuit per endpoint path.
+type Breaker struct {
+	cfg BreakerConfig
+
+	lock     sync.Mutex
+	circuits map[string]*circuit
+	now      func() time.Time
+}
+
+type circuit struct {
+	state       BreakerState
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int // half-open requests in flight
+	probeOK     int // half-open requests succeeded
+}
+
+// NewBreaker returns a Breaker for cfg.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.ConsecutiveFailures == 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = DefaultBreakerFailure
+	}
+	return &Breaker{cfg: cfg, circuits: make(map[string]*circuit), now: time.Now}
+}
+
+// BreakerTicket is an admitted request. Exactly one of Done or Cancel must
+// be called on it.
+type BreakerTicket struct {
+	b     *Breaker
+	path  string
+	probe bool
+}
+
+// Allow admits a request to path, or returns a *BreakerOpenError.
+func (b *Breaker) Allow(path string) (*BreakerTicket, error) {
+	b.lock.Lock()
+	now := b.now()
+	c := b.circuit(path, now)
+	from := c.state
+
+	if c.state == BreakerOpen && now.Sub(c.openedAt) >= b.cfg.OpenTimeout {
+		c.state = BreakerHalfOpen
+		c.probes, c.probeOK = 0, 0
+	}
+	var err error
+	probe := false
+	switch c.state {
+	case BreakerOpen:
+		err = &BreakerOpenError{Path: path, State: BreakerOpen, RetryAt: c.openedAt.Add(b.cfg.OpenTimeout)}
+	case BreakerHalfOpen:
+		if c.probes+c.probeOK >= b.cfg.HalfOpenProbes {
+			err = &BreakerOpenError{Path: path, State: BreakerHalfOpen, RetryAt: now.Add(b.cfg.OpenTimeout)}
+		} else {
+			c.probes++
+			probe = true
+		}
+	}
+	to := c.state
+	b.lock.Unlock()
+
+	b.notify(path, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &BreakerTicket{b: b, path: path, probe: probe}, nil
+}
+
+// Done records the outcome of the request.
+func (t *BreakerTicket) Done(err error) {
+	t.b.record(t.path, t.probe, t.b.cfg.IsFailure(err), true)
+}
+
+// Cancel gives up the ticket without recording an outcome, e.g. when the
+// request was never sent.
+func (t *BreakerTicket) Cancel() {
+	t.b.record(t.path, t.probe, false, false)
+}
+
+func (b *Breaker) record(path string, probe, failed, counted bool) {
+	b.lock.Lock()
+	now := b.now()
+	c := b.circuit(path, now)
+	from := c.state
+
+	switch {
+	case probe && c.state == BreakerHalfOpen:
+		c.probes--
+		if !counted {
+			break
+		}
+		if failed {
+			b.open(c, now)
+		} else if c.probeOK++; c.probeOK >= b.cfg.HalfOpenProbes {
+			c.state = BreakerClosed
+			c.consecutive = 0
+			c.windowStart, c.requests, c.failures = now, 0, 0
+		}
+	case c.state == BreakerClosed && counted:
+		c.requests++
+		if failed {
+			c.failures++
+			c.consecutive++
+		} else {
+			c.consecutive = 0
+		}
+		if b.shouldTrip(c) {
+			b.open(c, now)
+		}
+	}
+	to := c.state
+	b.lock.Unlock()
+
+	b.notify(path, from, to)
+}
+
+func (b *Breaker) shouldTrip(c *circuit) bool {
+	if b.cfg.ConsecutiveFailures > 0 && c.consecutive >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	return b.cfg.FailureRate > 0 && c.requests >= b.cfg.MinRequests &&
+		float64(c.failures)/float64(c.requests) >= b.cfg.FailureRate
+}
+
+func (b *Breaker) open(c *circuit, now time.Time) {
+	c.state = BreakerOpen
+	c.openedAt = now
+	c.probes, c.probeOK = 0, 0
+}
+
+// circuit returns the circuit for path, starting a new failure-rate window
+// if the current one has elapsed. Callers hold b.lock.
+func (b *Breaker) circuit(path string, now time.Time) *circuit {
+	c, ok := b.circuits[path]
+	if !ok {
+		c = &circuit{windowStart: now}
+		b.circuits[path] = c
+	}
+	if now.Sub(c.windowStart) >= b.cfg.Window {
+		c.windowStart, c.requests, c.failures = now, 0, 0
+	}
+	return c
+}
+
+func (b *Breaker) notify(path string, from, to BreakerState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(path, from, to)
+	}
+}
+
+// State returns the state of the circuit for path. An open circuit whose
+// OpenTimeout has passed is reported as half-open.
+func (b *Breaker) State(path string) BreakerState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c, ok := b.circuits[path]
+	if !ok {
+		return BreakerClosed
+	}
+	if c.state == BreakerOpen && b.now().Sub(c.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return c.state
+}
+
+// States returns the state of every circuit that has seen a request.
+func (b *Breaker) States() map[string]BreakerState {
+	b.lock.Lock()
+	paths := make([]string, 0, len(b.circuits))
+	for path := range b.circuits {
+		paths = append(paths, path)
+	}
+	b.lock.Unlock()
+
+	states := make(map[string]BreakerState, len(paths))
+	for _, path := range paths {
+		states[path] = b.State(path)
+	}
+	return states
+}
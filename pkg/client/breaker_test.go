@@ -0,0 +1,243 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker_Transitions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var changes []string
+	b := NewBreaker(BreakerConfig{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         10 * time.Second,
+		OnStateChange: func(path string, from, to BreakerState) {
+			changes = append(changes, fmt.Sprintf("%s:%s->%s", path, from, to))
+		},
+	})
+	b.now = func() time.Time { return now }
+
+	fail := errors.New("connection refused")
+	call := func(path string, err error) error {
+		ticket, aErr := b.Allow(path)
+		if aErr != nil {
+			return aErr
+		}
+		ticket.Done(err)
+		return nil
+	}
+
+	// Business errors don't count; three transport failures in a row trip /a.
+	call("/a", fail)
+	call("/a", &APIError{StatusCode: 200, Code: 500})
+	call("/a", fail)
+	call("/a", fail)
+	if b.State("/a") != BreakerClosed {
+		t.Fatal("tripped before 3 consecutive failures")
+	}
+	call("/a", &APIError{StatusCode: 502})
+	if b.State("/a") != BreakerOpen {
+		t.Fatalf("Expected open, got %s", b.State("/a"))
+	}
+
+	err := call("/a", nil)
+	var openErr *BreakerOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrBreakerOpen) || !openErr.RetryAt.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("Expected BreakerOpenError, got %v", err)
+	}
+	// Other paths are independent.
+	if err := call("/b", nil); err != nil {
+		t.Fatalf("/b blocked by /a: %v", err)
+	}
+
+	// After OpenTimeout one probe is let through; a failed probe reopens.
+	now = now.Add(10 * time.Second)
+	probe, err := b.Allow("/a")
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if _, err := b.Allow("/a"); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("second concurrent probe admitted: %v", err)
+	}
+	probe.Done(fail)
+	if b.State("/a") != BreakerOpen {
+		t.Fatalf("failed probe did not reopen: %s", b.State("/a"))
+	}
+
+	// A cancelled probe frees its slot; a successful one closes the circuit.
+	now = now.Add(10 * time.Second)
+	probe, _ = b.Allow("/a")
+	probe.Cancel()
+	if err := call("/a", nil); err != nil {
+		t.Fatalf("probe after cancel rejected: %v", err)
+	}
+	if b.State("/a") != BreakerClosed {
+		t.Fatalf("Expected closed, got %s", b.State("/a"))
+	}
+
+	want := []string{
+		"/a:closed->open", "/a:open->half-open", "/a:half-open->open",
+		"/a:open->half-open", "/a:half-open->closed",
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("state changes:\nExpected: %v\nActual:   %v", want, changes)
+	}
+}
+
+func TestBreaker_FailureRate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(BreakerConfig{ConsecutiveFailures: -1, FailureRate: 0.5, MinRequests: 10, Window: time.Minute})
+	b.now = func() time.Time { return now }
+
+	// Alternate success and failure: 50% but only trips at MinRequests.
+	for i := 0; i < 10; i++ {
+		if b.State("/a") != BreakerClosed {
+			t.Fatalf("tripped after %d requests", i)
+		}
+		ticket, err := b.Allow("/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			ticket.Done(nil)
+		} else {
+			ticket.Done(errors.New("timeout"))
+		}
+	}
+	if b.State("/a") != BreakerOpen {
+		t.Fatalf("Expected open at 50%% of 10, got %s", b.State("/a"))
+	}
+
+	// A new window forgets old failures.
+	b2 := NewBreaker(BreakerConfig{ConsecutiveFailures: -1, FailureRate: 0.5, MinRequests: 2, Window: time.Minute})
+	b2.now = func() time.Time { return now }
+	ticket, _ := b2.Allow("/a")
+	ticket.Done(errors.New("timeout"))
+	now = now.Add(time.Minute)
+	ticket, _ = b2.Allow("/a")
+	ticket.Done(nil)
+	if b2.State("/a") != BreakerClosed || b2.States()["/a"] != BreakerClosed {
+		t.Errorf("failure from previous window counted")
+	}
+}
+
+func TestClient_BreakerMediaFallback(t *testing.T) {
+	var down int32
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/getSongUrl", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"code":0,"success":true,"data":{"mediaList":[{"fileType":"mp3","url":"http://cdn/1.mp3"}]}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	b := NewBreaker(BreakerConfig{ConsecutiveFailures: 2, OpenTimeout: time.Hour})
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithBreaker(b), WithMediaCache(NewMemoryMediaCache(0)))
+
+	if _, err := client.GetSongUrl(&GetSongUrlRequest{SongId: "1"}); err != nil {
+		t.Fatalf("GetSongUrl failed: %v", err)
+	}
+
+	atomic.StoreInt32(&down, 1)
+	for i := 0; i < 2; i++ {
+		var apiErr *APIError
+		if _, err := client.GetSongUrl(&GetSongUrlRequest{SongId: "1"}); !errors.As(err, &apiErr) {
+			t.Fatalf("Expected 502 APIError, got %v", err)
+		}
+	}
+	if b.State("/mcrc-sas/yinsuda/getSongUrl") != BreakerOpen {
+		t.Fatal("breaker did not open")
+	}
+
+	resp, err := client.GetSongUrl(&GetSongUrlRequest{SongId: "1"})
+	if err != nil || len(resp.MediaList) != 1 || resp.MediaList[0].Url != "http://cdn/1.mp3" {
+		t.Fatalf("Expected cached media, got %+v, %v", resp, err)
+	}
+	if _, err := client.GetSongUrl(&GetSongUrlRequest{SongId: "2"}); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("Expected ErrBreakerOpen for uncached song, got %v", err)
+	}
+	var vErr *ValidationError
+	if _, err := client.GetSongUrl(nil); !errors.As(err, &vErr) {
+		t.Fatalf("Expected ValidationError for a nil request, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("Expected 3 requests to reach the server, got %d", n)
+	}
+}
+
+func TestMemoryMediaCache_Bounds(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewMemoryMediaCache(time.Minute)
+	m.MaxEntries = 2
+	m.now = func() time.Time { return now }
+
+	m.Put("1", "", &GetSongUrlResponse{})
+	m.Put("2", "", &GetSongUrlResponse{})
+	m.Get("1", "") // 2 is now least recently used
+	m.Put("3", "", &GetSongUrlResponse{})
+	if _, ok := m.Get("2", ""); ok || m.Len() != 2 {
+		t.Fatalf("Expected 2 evicted, %d entries", m.Len())
+	}
+	if _, ok := m.Get("1", ""); !ok {
+		t.Fatal("recently used entry evicted")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := m.Get("1", ""); ok {
+		t.Fatal("expired entry returned")
+	}
+	if m.Len() != 1 {
+		t.Errorf("expired entry not removed, %d entries", m.Len())
+	}
+}
+
+func TestMemoryMediaCache_Expire(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	var m MemoryMediaCache // the zero value is usable
+	m.Put("0", "", &GetSongUrlResponse{})
+	m.now = func() time.Time { return now }
+
+	media := func(expire ...string) *GetSongUrlResponse {
+		resp := &GetSongUrlResponse{}
+		for _, e := range expire {
+			resp.MediaList = append(resp.MediaList, MediaInfo{Expire: e})
+		}
+		return resp
+	}
+	m.Put("1", "", media("2024-05-01 13:00:00", "2024-05-01 12:30:00"))
+	m.Put("2", "", media(strconv.FormatInt(now.Add(2*time.Hour).Unix(), 10)))
+	m.Put("3", "", media("soon"))
+
+	now = now.Add(45 * time.Minute)
+	if _, ok := m.Get("1", ""); ok {
+		t.Error("served media whose earliest Expire has passed")
+	}
+	for _, id := range []string{"0", "2", "3"} {
+		if _, ok := m.Get(id, ""); !ok {
+			t.Errorf("entry %s not served before expiry", id)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := m.Get("2", ""); ok {
+		t.Error("served media past its Unix Expire")
+	}
+	if m.Len() != 2 {
+		t.Errorf("expired entries not removed, %d entries", m.Len())
+	}
+}
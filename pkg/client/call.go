@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"reflect"
+)
+
+// Call sends req to path as JSON and returns the decoded data of the
+// response envelope. It is how the endpoint methods are built, and works for
+// endpoints this package does not wrap yet:
+//
+//	resp, err := client.Call[MyRequest, MyResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/newEndpoint", &MyRequest{...})
+//
+// A nil req (nil pointer, map or slice) sends no body. If req implements
+// Validator it is validated first, and a *ValidationError is returned without
+// sending; see WithValidation. Other errors are the same as DoContext's.
+func Call[Req, Resp any](ctx context.Context, c *Client, method, path string, req Req) (*Resp, error) {
+	var body interface{} = req
+	if isNilBody(body) {
+		body = nil
+	}
+	if err := c.validateRequest(body); err != nil {
+		return nil, err
+	}
+	var result Envelope[Resp]
+	if err := c.DoContext(ctx, method, path, nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+func isNilBody(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func (c *Client) validateRequest(req interface{}) error {
+	if !c.validate {
+		return nil
+	}
+	switch r := req.(type) {
+	case *GetSongInfoRequest:
+		return r.validate(c.maxSongInfoIds)
+	case *SearchSongRequest:
+		return r.validate(c.maxSearchLimit)
+	case Validator:
+		return r.Validate()
+	}
+	return nil
+}
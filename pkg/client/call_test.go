@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	type newRequest struct {
+		SongId string `json:"songId"`
+	}
+	type newResponse struct {
+		Lyrics string `json:"lyrics"`
+	}
+
+	var bodies []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	lookup := func(string) (string, error) { return "testAppSecret", nil }
+	mux.Handle("/mcrc-sas/yinsuda/getLyrics", VerifyMiddleware(lookup, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(b) == 0 {
+			w.Write([]byte(`{"code":400,"success":false,"message":"songId required"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"success":true,"data":{"lyrics":"故事的小黄花"}}`))
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := NewClient("testAppId", "testAppSecret", server.URL)
+
+	resp, err := Call[*newRequest, newResponse](context.Background(), c, "POST", "/mcrc-sas/yinsuda/getLyrics", &newRequest{SongId: "1"})
+	if err != nil || resp.Lyrics != "故事的小黄花" {
+		t.Fatalf("Call failed: %+v %v", resp, err)
+	}
+
+	// A nil request pointer sends no body rather than "null".
+	_, err = Call[*newRequest, newResponse](context.Background(), c, "POST", "/mcrc-sas/yinsuda/getLyrics", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 400 {
+		t.Fatalf("Expected APIError 400, got %v", err)
+	}
+	if bodies[0] != `{"songId":"1"}` || bodies[1] != "" {
+		t.Errorf("unexpected bodies %q", bodies)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Call[*newRequest, newResponse](ctx, c, "POST", "/mcrc-sas/yinsuda/getLyrics", &newRequest{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
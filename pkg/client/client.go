@@ -1,10 +1,14 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
@@ -18,33 +22,123 @@ type Client struct {
 	tokenProvider *TokenProvider
 	httpClient   *http.Client
 	baseUrl      string
+	signer       Signer
+	signTraceLog func(format string, args ...interface{})
+	queryEncoder QueryEncoder
+	limiter      *Limiter
+	breaker      *Breaker
+	mediaCache   MediaCache
+	interceptors []Interceptor
+	handler      Handler
+	logger       *slog.Logger
+	tracer       Tracer
+	metrics      Metrics
+
+	maxResponseSize int64
+	maxStreamSize   int64
+	maxErrorBody    int
+	validate        bool
+	maxSongInfoIds  int
+	maxSearchLimit  int
+	defaults        RequestDefaults
 }
 
 // NewClient creates a new Yinsuda Music API client.
 // baseUrl should be the root URL of the API, e.g., "https://api.yinsuda.com"
-func NewClient(appId, appSecret, baseUrl string) *Client {
+func NewClient(appId, appSecret, baseUrl string, opts ...Option) *Client {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 	// Assume the auth endpoint is relative to baseUrl, e.g. /oauth2/token
 	authUrl := fmt.Sprintf("%s/oauth2/token", baseUrl)
 	
-	return &Client{
+	c := &Client{
 		appId:        appId,
 		tokenProvider: NewTokenProvider(appId, appSecret, authUrl, httpClient),
 		httpClient:   httpClient,
 		baseUrl:      baseUrl,
+		signer:       MD5Signer{},
+		tracer:       noopTracer{},
+		metrics:      noopMetrics{},
+
+		maxResponseSize: DefaultMaxResponseSize,
+		maxErrorBody:    DefaultMaxErrorBody,
+		validate:        true,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.defaults = c.defaults.withBuiltins()
+	c.handler = Chain(c.send, c.interceptors...)
+	return c
 }
 
 // Do performs a request to the API, handling authentication and signing.
 // method: GET, POST, etc.
 // path: relative path, e.g., "/musician/list"
 // body: request body struct (will be marshaled to JSON), or nil
-// result: pointer to struct where response data will be unmarshaled
+// result: pointer to struct where response data will be unmarshaled; an
+// *Envelope[T] is decoded in one pass, anything else shaped like the
+// envelope is decoded after the envelope is checked
 func (c *Client) Do(method, path string, query url.Values, body interface{}, result interface{}) error {
+	return c.DoContext(context.Background(), method, path, query, body, result)
+}
+
+// DoContext is Do with a context. ctx bounds the wait on the Limiter as well
+// as the HTTP request itself.
+//
+// With WithBreaker, requests to a path whose circuit is open fail fast with a
+// *BreakerOpenError before waiting on the limiter.
+func (c *Client) DoContext(ctx context.Context, method, path string, query url.Values, body interface{}, result interface{}) error {
+	ctx, span := c.tracer.Start(ctx, "yinsuda "+path, Attribute{AttrPath, path}, Attribute{AttrMethod, method})
+	defer span.End()
+
+	start := time.Now()
+	ex, err := c.doGuarded(ctx, method, path, query, body, result)
+	c.observe(ctx, span, path, start, ex, err)
+	return err
+}
+
+// doGuarded runs do behind the breaker and the limiter. The Exchange is nil
+// if the request was never built.
+func (c *Client) doGuarded(ctx context.Context, method, path string, query url.Values, body interface{}, result interface{}) (*Exchange, error) {
+	// 0a. Circuit breaker
+	var ticket *BreakerTicket
+	if c.breaker != nil {
+		var err error
+		if ticket, err = c.breaker.Allow(path); err != nil {
+			if c.logger != nil {
+				c.logger.LogAttrs(ctx, slog.LevelWarn, "yinsuda request short-circuited", slog.String("path", path), errorAttr(err))
+			}
+			return nil, err
+		}
+	}
+
+	// 0b. Wait for the limiter
+	if c.limiter != nil {
+		release, err := c.limiter.Wait(ctx, path)
+		if err != nil {
+			if ticket != nil {
+				ticket.Cancel()
+			}
+			if c.logger != nil {
+				c.logger.LogAttrs(ctx, slog.LevelWarn, "yinsuda rate limiter wait aborted", slog.String("path", path), errorAttr(err))
+			}
+			return nil, fmt.Errorf("waiting for rate limiter: %w", err)
+		}
+		defer release()
+	}
+
+	ex, err := c.do(ctx, method, path, query, body, result)
+	if ticket != nil {
+		ticket.Done(err)
+	}
+	return ex, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, result interface{}) (*Exchange, error) {
 	// 1. Get Access Token
-	accessToken, err := c.tokenProvider.GetAccessToken()
+	accessToken, err := c.tokenProvider.GetAccessTokenContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get access token: %w", err)
+		return nil, fmt.Errorf("failed to get access token: %w", err)
 	}
 
 	// 2. Prepare Body
@@ -52,7 +146,7 @@ func (c *Client) Do(method, path string, query url.Values, body interface{}, res
 	if body != nil {
 		bodyBytes, err = json.Marshal(body)
 		if err != nil {
-			return fmt.Errorf("failed to marshal body: %w", err)
+			return nil, fmt.Errorf("failed to marshal body: %w", err)
 		}
 	}
 
@@ -64,25 +158,26 @@ func (c *Client) Do(method, path string, query url.Values, body interface{}, res
 		AppId:       c.appId,
 		AccessToken: accessToken,
 		Timestamp:   timestamp,
-		SignMethod:  "md5",
+		SignMethod:  c.signer.Method(),
 		TraceId:     traceId,
 		// SignVersion excluded from calculation based on Java ref
 	}
 
 	// 4. Calculate Sign
-	// Note: We need to pass the query values separately if it's a GET request or has query params
+	// The query is encoded once so the signed and the sent query are byte-identical.
 	// path should strictly be the path, e.g., /foo/bar.
-	sign := CalculateSign(signParams, bodyBytes, path, query, c.tokenProvider.appSecret)
+	rawQuery := c.queryEncoder.Encode(query)
+	sign := c.signer.Sign(signParams, bodyBytes, path, rawQuery, c.tokenProvider.appSecret)
 
 	// 5. Construct Request
 	fullUrl := fmt.Sprintf("%s%s", c.baseUrl, path)
-	if len(query) > 0 {
-		fullUrl += "?" + query.Encode()
+	if rawQuery != "" {
+		fullUrl += "?" + rawQuery
 	}
 
-	req, err := http.NewRequest(method, fullUrl, bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, method, fullUrl, bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// 6. Set Headers
@@ -91,52 +186,153 @@ func (c *Client) Do(method, path string, query url.Values, body interface{}, res
 	q.Set("appId", c.appId)
 	q.Set("accessToken", accessToken)
 	q.Set("timestamp", timestamp)
-	q.Set("signMethod", "md5")
+	q.Set("signMethod", c.signer.Method())
 	q.Set("traceId", traceId)
 	q.Set("sign", sign)
-	q.Set("signVersion", "v2")
+	q.Set("signVersion", c.signer.Version())
 	// "source" is optional, not setting it for now.
 
-	// 7. Execute
-	resp, err := c.httpClient.Do(req)
+	// 7. Execute through the interceptor chain
+	ex := &Exchange{Path: path, Request: req, RequestBody: bodyBytes, decode: result != nil, result: result}
+	start := time.Now()
+	err = c.handler(ex)
+	c.logRequest(ctx, ex, start, err)
+	if err != nil {
+		c.logSignTrace(err, signParams, bodyBytes, path, rawQuery)
+		return ex, err
+	}
+
+	// 8. Parse Response
+	// If result is expected and send has not already decoded it
+	if result != nil && !ex.decoded {
+		// Now unmarshal into the specific result
+		if err := json.Unmarshal(ex.ResponseBody, result); err != nil {
+			return ex, fmt.Errorf("failed to unmarshal into result: %w", err)
+		}
+	}
+
+	return ex, nil
+}
+
+// send is the innermost Handler: it executes ex.Request, reads the body and
+// checks the envelope.
+func (c *Client) send(ex *Exchange) error {
+	ex.sends++
+	if ex.sends > 1 && ex.Request.GetBody != nil {
+		// An interceptor is resending; the previous attempt consumed the body.
+		rewound, err := ex.Request.GetBody()
+		if err != nil {
+			return fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		ex.Request.Body = rewound
+	}
+	resp, err := c.httpClient.Do(ex.Request)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	ex.Response = resp
 
 	if resp.StatusCode != http.StatusOK {
-		bodyDump, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyDump))
+		if c.limiter != nil {
+			c.limiter.Observe(ex.Path, resp.StatusCode, 0, parseRetryAfter(resp.Header, time.Now()))
+		}
+		body, truncated := readCapped(resp.Body, c.maxErrorBody)
+		ex.ResponseBody = body
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body), Truncated: truncated}
+	}
+	if !ex.decode {
+		return nil
 	}
 
-	// 8. Parse Response
-	// If result is expected
-	if result != nil {
-		respBody, err := io.ReadAll(resp.Body)
+	// A streaming result decodes straight from the connection; ResponseBody
+	// stays nil.
+	var h envelopeHeader
+	decoded := false
+	// Gateways and proxies sometimes answer 200 with an HTML page; that is
+	// detected from the first byte, before reading the rest.
+	// Streamed bodies are never held in memory, so they have a limit of
+	// their own.
+	sd, streaming := ex.result.(streamDecoder)
+	limit := c.maxResponseSize
+	if streaming {
+		limit = c.maxStreamSize
+	}
+	body := bufio.NewReader(newLimitedBody(resp.Body, limit, resp.StatusCode))
+	if !peekJSON(body) {
+		return c.nonJSONError(resp, body)
+	}
+	if streaming {
+		if h, err = sd.decodeStream(body); err != nil {
+			return err
+		}
+		decoded = true
+	} else {
+		ex.ResponseBody, err = io.ReadAll(body)
 		if err != nil {
+			var tooLarge *ResponseTooLargeError
+			if errors.As(err, &tooLarge) {
+				return err
+			}
 			return fmt.Errorf("failed to read response body: %w", err)
 		}
 
 		// It seems the API always returns a standard envelope.
-		// We can try to decode strictly if the user provided a struct matching the inner data,
-		// or matching the full envelope. Data models usually just want the 'data' part,
-		// but checking 'code' is important.
-		
-		// Let's decode into BaseResponse first to check generic errors.
-		var baseResp BaseResponse
-		if err := json.Unmarshal(respBody, &baseResp); err != nil {
+		// The envelope is parsed once: an *Envelope[T] result is filled in the
+		// same pass, other results only get the header checked here.
+		h, decoded, err = decodeEnvelope(ex.ResponseBody, ex.result)
+		if err != nil {
+			if _, typed := ex.result.(enveloper); typed {
+				return fmt.Errorf("failed to unmarshal into result: %w", err)
+			}
 			return fmt.Errorf("failed to parse base response: %w", err)
 		}
+	}
+	ex.decoded = decoded
+	ex.Envelope = &BaseResponse{Code: h.Code, Message: h.Message, TraceId: h.TraceId, Success: h.Success, Msg: h.Msg}
 
-		if !baseResp.Success || baseResp.Code != 0 {
-			return fmt.Errorf("API error: %s (%s) %s", baseResp.Message, baseResp.Msg, string(respBody))
+	if !h.Success || h.Code != 0 {
+		if c.limiter != nil {
+			c.limiter.Observe(ex.Path, resp.StatusCode, h.Code, parseRetryAfter(resp.Header, time.Now()))
 		}
-
-		// Now unmarshal into the specific result
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to unmarshal into result: %w", err)
+		errBody, truncated := capBody(ex.ResponseBody, c.maxErrorBody)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       h.Code,
+			Message:    h.Message,
+			Msg:        h.Msg,
+			TraceId:    h.TraceId,
+			Body:       string(errBody),
+			Truncated:  truncated,
 		}
 	}
-
 	return nil
 }
+
+// logSignTrace logs the signature inputs if err is a sign rejection and
+// WithSignTraceLog is set.
+func (c *Client) logSignTrace(err error, params SignParams, body []byte, path, rawQuery string) {
+	if c.signTraceLog == nil || !IsSignError(err) {
+		return
+	}
+	if _, ok := c.signer.(MD5Signer); !ok {
+		return
+	}
+	trace := TraceSignRaw(params, body, path, rawQuery, c.tokenProvider.appSecret)
+	c.signTraceLog("yinsuda: sign rejected for %s (traceId %s): %s", path, params.TraceId, trace.Redacted())
+}
+
+// Limiter returns the limiter set by WithLimiter, or nil.
+func (c *Client) Limiter() *Limiter {
+	return c.limiter
+}
+
+// Breaker returns the breaker set by WithBreaker, or nil.
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// TokenProvider returns the provider the client fetches access tokens from.
+func (c *Client) TokenProvider() *TokenProvider {
+	return c.tokenProvider
+}
@@ -0,0 +1,61 @@
+package client
+
+// RequestDefaults are the values the endpoint methods fill into zero request
+// fields before sending. They are applied to a copy: the caller's request is
+// never modified, so one request value may be shared between goroutines.
+//
+// SongListLimit always has a value; a zero SongListLimit takes the built-in
+// default. The search defaults are opt-in: left zero, SearchSong sends the
+// caller's Limit and SearchType as they are.
+type RequestDefaults struct {
+	// SongListLimit is the GetSongList and StreamSongList page size when
+	// Limit is 0. Defaults to DefaultSongListLimit.
+	SongListLimit int
+	// SearchLimit, if set, is the SearchSong and StreamSearchSong page size
+	// when Limit is 0.
+	SearchLimit int
+	// SearchType, if set, is used when a SearchSongRequest leaves
+	// SearchType 0.
+	SearchType SearchType
+}
+
+// DefaultSongListLimit is the GetSongList page size used when neither the
+// request nor WithRequestDefaults sets one.
+const DefaultSongListLimit = 100
+
+// withBuiltins fills the zero fields of d with the built-in defaults.
+func (d RequestDefaults) withBuiltins() RequestDefaults {
+	if d.SongListLimit == 0 {
+		d.SongListLimit = DefaultSongListLimit
+	}
+	return d
+}
+
+// songList returns a copy of req with the defaults applied. A nil req is
+// treated as the zero request.
+func (d RequestDefaults) songList(req *GetSongListRequest) *GetSongListRequest {
+	var r GetSongListRequest
+	if req != nil {
+		r = *req
+	}
+	if r.Limit == 0 {
+		r.Limit = d.SongListLimit
+	}
+	return &r
+}
+
+// search returns a copy of req with the defaults applied, keeping a status
+// set through SetStatus. A nil req is treated as the zero request.
+func (d RequestDefaults) search(req *SearchSongRequest) *SearchSongRequest {
+	var r SearchSongRequest
+	if req != nil {
+		r = *req
+	}
+	if r.Limit == 0 {
+		r.Limit = d.SearchLimit
+	}
+	if r.SearchType == 0 {
+		r.SearchType = d.SearchType
+	}
+	return &r
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// newDefaultsServer answers every endpoint with an empty success envelope and
+// records the limit and searchType of each request body.
+func newDefaultsServer(t *testing.T) (*httptest.Server, func() []map[string]interface{}) {
+	var (
+		lock sync.Mutex
+		seen []map[string]interface{}
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		lock.Lock()
+		seen = append(seen, body)
+		lock.Unlock()
+		w.Write([]byte(`{"code":0,"success":true,"data":{"total":1,"nextQueryInfo":"END","songList":[{"songId":"1"}],"mediaList":[]}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, func() []map[string]interface{} {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]map[string]interface{}(nil), seen...)
+	}
+}
+
+func TestRequestDefaults(t *testing.T) {
+	server, seen := newDefaultsServer(t)
+
+	c := NewClient("testAppId", "testAppSecret", server.URL)
+	if _, err := c.GetSongList(&GetSongListRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetSongList(nil); err != nil {
+		t.Fatal(err)
+	}
+	// No search defaults unless asked for: the request is sent as is.
+	if _, err := c.SearchSong(&SearchSongRequest{SearchText: "晴天", SearchType: SearchTypeFull, Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	var vErr *ValidationError
+	if _, err := c.SearchSong(&SearchSongRequest{SearchText: "晴天"}); !errors.As(err, &vErr) {
+		t.Fatalf("Expected ValidationError for zero limit and type, got %v", err)
+	}
+
+	custom := NewClient("testAppId", "testAppSecret", server.URL,
+		WithRequestDefaults(RequestDefaults{SongListLimit: 50, SearchLimit: 20, SearchType: SearchTypeArtistName}))
+	if _, err := custom.StreamSongList(context.Background(), &GetSongListRequest{}, func(Song) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := custom.StreamSearchSong(context.Background(), &SearchSongRequest{SearchText: "晴天"}, func(Song) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	// Explicit values win over defaults.
+	if _, err := custom.SearchSong(&SearchSongRequest{SearchText: "晴天", SearchType: SearchTypeSongName, Limit: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []map[string]interface{}{
+		{"limit": 100.0},
+		{"limit": 100.0},
+		{"limit": 10.0, "searchType": 1.0},
+		{"limit": 50.0},
+		{"limit": 20.0, "searchType": 3.0},
+		{"limit": 5.0, "searchType": 2.0},
+	}
+	got := seen()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d requests, got %d", len(want), len(got))
+	}
+	for i, w := range want {
+		for k, v := range w {
+			if got[i][k] != v {
+				t.Errorf("request %d: Expected %s=%v, got %v", i, k, v, got[i][k])
+			}
+		}
+	}
+}
+
+// TestClient_SharedRequests drives the client from many goroutines with the
+// same request values. Run with -race: any write to a shared request fails.
+func TestClient_SharedRequests(t *testing.T) {
+	server, seen := newDefaultsServer(t)
+	c := NewClient("testAppId", "testAppSecret", server.URL, WithMediaCache(NewMemoryMediaCache(0)))
+
+	list := &GetSongListRequest{QueryInfo: "cursor"}
+	search := &SearchSongRequest{SearchText: "晴天", SearchType: SearchTypeFull, Limit: 10}
+	search.SetStatus(0)
+	url := &GetSongUrlRequest{SongId: "1"}
+	page := &PageRequest{Length: 10}
+	listBefore, searchBefore, urlBefore, pageBefore := *list, *search, *url, *page
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			noop := func(Song) error { return nil }
+			for _, err := range []error{
+				second(c.GetSongList(list)),
+				second(c.StreamSongList(ctx, list, noop)),
+				second(c.SearchSong(search)),
+				second(c.StreamSearchSong(ctx, search, noop)),
+				second(c.GetSongUrl(url)),
+				second(c.QuerySongListPage(page)),
+				second(c.QueryRankingListPage(page)),
+			} {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(*list, listBefore) || !reflect.DeepEqual(*search, searchBefore) ||
+		!reflect.DeepEqual(*url, urlBefore) || !reflect.DeepEqual(*page, pageBefore) {
+		t.Errorf("shared requests were modified: %+v %+v %+v %+v", *list, *search, *url, *page)
+	}
+	if n := len(seen()); n != workers*7 {
+		t.Errorf("Expected %d requests, got %d", workers*7, n)
+	}
+	for _, body := range seen() {
+		if body["queryInfo"] == "cursor" && body["limit"] != 100.0 {
+			t.Errorf("song list sent without default limit: %v", body)
+		}
+		if body["searchText"] == "晴天" && body["status"] != 0.0 {
+			t.Errorf("search lost its status filter: %v", body)
+		}
+	}
+}
+
+func second[T any](_ T, err error) error {
+	return err
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"encoding/json"
+)
+
+// Envelope is the standard response envelope with a typed Data. Passing a
+// *Envelope[T] as Do's result decodes the body in a single pass.
+type Envelope[T any] struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+	TraceId string `json:"traceId"`
+	Success bool   `json:"success"`
+	Msg     string `json:"msg"`
+}
+
+// envelopeHeader is the envelope without data. Unmarshalling into it skips
+// data without building anything.
+type envelopeHeader struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	TraceId string `json:"traceId"`
+	Success bool   `json:"success"`
+	Msg     string `json:"msg"`
+}
+
+// enveloper is implemented by *Envelope[T].
+type enveloper interface {
+	header() envelopeHeader
+}
+
+func (e *Envelope[T]) header() envelopeHeader {
+	return envelopeHeader{Code: e.Code, Message: e.Message, TraceId: e.TraceId, Success: e.Success, Msg: e.Msg}
+}
+
+// decodeEnvelope parses the envelope of body and, when result is an
+// *Envelope[T], result itself in the same pass; decoded reports whether it
+// did. Other results are left for the caller. If a typed decode fails, the
+// header is still recovered so a business error is not masked by a data
+// shape mismatch; the decode error is returned only for successful envelopes.
+func decodeEnvelope(body []byte, result interface{}) (h envelopeHeader, decoded bool, err error) {
+	if env, ok := result.(enveloper); ok {
+		if err := json.Unmarshal(body, result); err == nil {
+			return env.header(), true, nil
+		} else if hErr := json.Unmarshal(body, &h); hErr != nil || (h.Success && h.Code == 0) {
+			return h, false, err
+		}
+		return h, false, nil
+	}
+	err = json.Unmarshal(body, &h)
+	return h, false, err
+}
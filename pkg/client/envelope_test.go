@@ -0,0 +1,146 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeEnvelope(t *testing.T) {
+	// Typed envelope: one pass.
+	var page Envelope[GetSongListResponse]
+	h, decoded, err := decodeEnvelope([]byte(`{"code":0,"success":true,"traceId":"t1","data":{"nextQueryInfo":"END","songList":[{"songId":"1"}]}}`), &page)
+	if err != nil || !decoded || h.TraceId != "t1" || page.Data.NextQueryInfo != "END" || len(page.Data.SongList) != 1 {
+		t.Fatalf("typed decode: %+v %v %v %+v", h, decoded, err, page)
+	}
+
+	// A business error whose data doesn't fit T is still reported as such.
+	var info Envelope[GetSongInfoResponse]
+	h, decoded, err = decodeEnvelope([]byte(`{"code":1001,"success":false,"message":"bad","data":""}`), &info)
+	if err != nil || decoded || h.Code != 1001 || h.Message != "bad" {
+		t.Fatalf("error envelope: %+v %v %v", h, decoded, err)
+	}
+
+	// A successful envelope with the wrong data shape is a decode error.
+	if _, _, err := decodeEnvelope([]byte(`{"code":0,"success":true,"data":""}`), &info); err == nil {
+		t.Fatal("Expected decode error for mismatched data")
+	}
+
+	// Other results only get the header.
+	var legacy struct {
+		Data GetSongInfoResponse `json:"data"`
+	}
+	h, decoded, err = decodeEnvelope([]byte(`{"code":0,"success":true,"data":{"songList":[]}}`), &legacy)
+	if err != nil || decoded || !h.Success {
+		t.Fatalf("legacy result: %+v %v %v", h, decoded, err)
+	}
+}
+
+func TestClient_EnvelopeResult(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"success":true,"traceId":"srv","data":{"total":2}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := NewClient("testAppId", "testAppSecret", server.URL)
+
+	var typed Envelope[SearchSongResponse]
+	if err := client.Do("POST", "/api/data", nil, nil, &typed); err != nil || typed.Data.Total != 2 || typed.TraceId != "srv" {
+		t.Fatalf("Envelope result: %+v %v", typed, err)
+	}
+	var legacy struct {
+		Data SearchSongResponse `json:"data"`
+	}
+	if err := client.Do("POST", "/api/data", nil, nil, &legacy); err != nil || legacy.Data.Total != 2 {
+		t.Fatalf("legacy result: %+v %v", legacy, err)
+	}
+}
+
+// songPage is a getSongList response with n fully populated songs.
+func songPage(n int) []byte {
+	page := Envelope[GetSongListResponse]{Code: 0, Success: true, TraceId: "trace"}
+	page.Data.NextQueryInfo = "cursor"
+	for i := 0; i < n; i++ {
+		page.Data.SongList = append(page.Data.SongList, Song{
+			SongId:         fmt.Sprintf("%08d", i),
+			SongName:       "晴天",
+			CompanyName:    "杰威尔音乐有限公司",
+			PublicTime:     "2003-07-31",
+			Version:        "原唱",
+			Duration:       269,
+			Status:         1,
+			GrantStatus:    1,
+			GrantStartTime: "2023-01-01 00:00:00",
+			Language:       "国语",
+			PitchUrl:       "https://cdn.example.com/pitch/" + fmt.Sprint(i),
+			ChorusStartMS:  60000,
+			ChorusEndMS:    90000,
+			CopyrightList:  []Copyright{{SceneId: "1", TerminalIdList: []string{"1", "2", "3"}}},
+			Album: Album{AlbumId: "A1", AlbumName: "叶惠美", ImagePathMapList: []ImagePathMap{
+				{Key: "300", Value: "https://cdn.example.com/a/300.jpg"},
+				{Key: "500", Value: "https://cdn.example.com/a/500.jpg"},
+			}},
+			ArtistList: []Artist{{ArtistId: "R1", ArtistName: "周杰伦"}},
+			LrcList:    []Lrc{{Type: "lrc", Url: "https://cdn.example.com/lrc/1.lrc"}},
+		})
+	}
+	body, _ := json.Marshal(page)
+	return body
+}
+
+// BenchmarkDecode_BaseResponse is the decoding Do did before Envelope: the
+// whole body into BaseResponse, then again into the result.
+func BenchmarkDecode_BaseResponse(b *testing.B) {
+	body := songPage(100)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var base BaseResponse
+		if err := json.Unmarshal(body, &base); err != nil {
+			b.Fatal(err)
+		}
+		var result struct {
+			Data GetSongListResponse `json:"data"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecode_HeaderThenResult is Do with a result that is not an Envelope.
+func BenchmarkDecode_HeaderThenResult(b *testing.B) {
+	body := songPage(100)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var result struct {
+			Data GetSongListResponse `json:"data"`
+		}
+		if _, _, err := decodeEnvelope(body, &result); err != nil {
+			b.Fatal(err)
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecode_Envelope is Do with an *Envelope[T] result.
+func BenchmarkDecode_Envelope(b *testing.B) {
+	body := songPage(100)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var result Envelope[GetSongListResponse]
+		if _, _, err := decodeEnvelope(body, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
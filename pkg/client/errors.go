@@ -0,0 +1,98 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// APIError is returned by Do when the API answers with a non-200 HTTP status
+// or with an envelope whose code/success signal failure.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Code       int    // business code from the envelope; 0 for HTTP errors
+	Message    string // envelope "message"
+	Msg        string // envelope "msg"
+	TraceId    string // envelope "traceId"
+	Body       string // raw response body, cut to the WithMaxErrorBody limit
+	Truncated  bool   // Body was cut
+}
+
+func (e *APIError) Error() string {
+	body := e.Body
+	if e.Truncated {
+		body += "...(truncated)"
+	}
+	if e.StatusCode != 200 {
+		return fmt.Sprintf("API returned status %d: %s", e.StatusCode, body)
+	}
+	return fmt.Sprintf("API error: %s (%s) %s", e.Message, e.Msg, body)
+}
+
+// AuthError is returned when an access token cannot be obtained.
+type AuthError struct {
+	StatusCode int    // HTTP status of the token request; 0 if it was never answered
+	Code       string // business code from the token response, if any
+	Err        error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// IsSignError reports whether err is an API rejection that blames the
+// signature. The platform does not document a dedicated code, so this
+// matches known phrasings in the message text (and, for 401/403, in the
+// body): "signature", "签名", or "sign" next to words such as error,
+// mismatch or invalid. Words that merely contain "sign", like "design" or
+// "assign", do not match.
+func IsSignError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	text := apiErr.Message + " " + apiErr.Msg
+	if apiErr.StatusCode == 401 || apiErr.StatusCode == 403 {
+		text += " " + apiErr.Body
+	}
+	return signErrorPattern.MatchString(strings.ToLower(text))
+}
+
+var signErrorPattern = regexp.MustCompile(`签名` +
+	`|\bsignature\b` +
+	`|\b(?:invalid|illegal|wrong|bad|incorrect|unsupported)[ _-]?sign(?:ature)?(?:[ _-]?method)?\b` +
+	`|\bsign(?:ature)?[ _-]?(?:error|mismatch|invalid|incorrect|wrong|fail(?:ed|ure)?|verif\w*|check\w*)\b` +
+	`|\bsign(?:ature)?[ _-]?(?:is|was)?[ _-]?(?:invalid|incorrect|wrong)\b`)
+
+// ResponseTooLargeError is returned when a response body exceeds the limit
+// set by WithMaxResponseSize.
+type ResponseTooLargeError struct {
+	StatusCode int
+	Limit      int64 // bytes
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes (status %d)", e.Limit, e.StatusCode)
+}
+
+// NonJSONError is returned when a 200 response is not a JSON envelope, e.g.
+// an HTML page served by a proxy or gateway.
+type NonJSONError struct {
+	StatusCode  int
+	ContentType string
+	Body        string // start of the body, at most the WithMaxErrorBody limit
+	Truncated   bool   // Body was cut
+}
+
+func (e *NonJSONError) Error() string {
+	snippet := e.Body
+	if e.Truncated {
+		snippet += "...(truncated)"
+	}
+	return fmt.Sprintf("API returned non-JSON response (status %d, %s): %s", e.StatusCode, e.ContentType, snippet)
+}
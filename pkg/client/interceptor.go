@@ -0,0 +1,148 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// Exchange is one API call as seen by interceptors.
+type Exchange struct {
+	// Path is the endpoint path passed to Do, e.g. "/mcrc-sas/yinsuda/getSongList".
+	Path string
+	// Request is the signed request. Its body has not been read yet.
+	Request *http.Request
+	// RequestBody is the JSON body that was signed; nil for no body.
+	RequestBody []byte
+
+	// The fields below are set by the time next returns.
+
+	// Response is the raw response, or nil if none was received. Its body
+	// is already consumed and closed; use ResponseBody.
+	Response *http.Response
+	// ResponseBody is the raw response body. It is only read for non-200
+	// responses and for calls that decode a result.
+	ResponseBody []byte
+	// Envelope is the decoded envelope, or nil if it was not decoded. Its
+	// Data is left nil; the data is only decoded into Do's result.
+	Envelope *BaseResponse
+
+	decode  bool
+	result  interface{}
+	decoded bool // result was filled while parsing the envelope
+	sends   int  // times the request reached the transport
+}
+
+// Handler sends an Exchange and fills in its response fields. The error is
+// what Do returns, e.g. an *APIError.
+type Handler func(ex *Exchange) error
+
+// Interceptor wraps a Handler. It may act on ex before calling next (the
+// request is already signed), after it (the response is decoded), or both.
+type Interceptor func(next Handler) Handler
+
+// Chain wraps h in interceptors, the first being the outermost.
+func Chain(h Handler, interceptors ...Interceptor) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		h = interceptors[i](h)
+	}
+	return h
+}
+
+// LoggingInterceptor logs one line per call through logf, e.g.
+//
+//	yinsuda: POST /mcrc-sas/yinsuda/getSongInfo status=200 code=0 traceId=... took=12ms
+//
+// Headers and bodies are not logged, and errors are passed through Redact.
+// log.Printf fits logf.
+func LoggingInterceptor(logf func(format string, args ...interface{})) Interceptor {
+	return func(next Handler) Handler {
+		return func(ex *Exchange) error {
+			start := time.Now()
+			err := next(ex)
+			took := time.Since(start).Round(time.Millisecond)
+
+			status, code := 0, 0
+			if ex.Response != nil {
+				status = ex.Response.StatusCode
+			}
+			if ex.Envelope != nil {
+				code = ex.Envelope.Code
+			}
+			traceId := ex.Request.Header.Get("traceId")
+			if err != nil {
+				logf("yinsuda: %s %s status=%d code=%d traceId=%s took=%s err=%s", ex.Request.Method, ex.Path, status, code, traceId, took, loggedError(err))
+			} else {
+				logf("yinsuda: %s %s status=%d code=%d traceId=%s took=%s", ex.Request.Method, ex.Path, status, code, traceId, took)
+			}
+			return err
+		}
+	}
+}
+
+// signedHeaders are set by Do and covered by, or part of, the signature.
+var signedHeaders = []string{"appId", "accessToken", "timestamp", "signMethod", "traceId", "sign", "signVersion", "source"}
+
+// HeaderInterceptor adds h to every request, replacing existing values.
+// Headers that take part in signing are left alone, since changing them
+// would invalidate the signature.
+func HeaderInterceptor(h http.Header) Interceptor {
+	add := h.Clone()
+	for _, name := range signedHeaders {
+		add.Del(name)
+	}
+	return func(next Handler) Handler {
+		return func(ex *Exchange) error {
+			for k, v := range add {
+				ex.Request.Header[k] = append([]string(nil), v...)
+			}
+			return next(ex)
+		}
+	}
+}
+
+// Capture is a request/response pair recorded by BodyCaptureInterceptor.
+type Capture struct {
+	Method       string
+	Path         string
+	TraceId      string
+	StatusCode   int // 0 if no response was received
+	RequestBody  []byte
+	ResponseBody []byte
+	Truncated    bool // a body was cut to the capture limit
+	Duration     time.Duration
+	Err          error
+}
+
+// BodyCaptureInterceptor passes a Capture of every call to sink, e.g. for an
+// audit log. Bodies are copied and cut to maxBody bytes; maxBody <= 0 keeps
+// them whole. Headers are not captured.
+func BodyCaptureInterceptor(maxBody int, sink func(Capture)) Interceptor {
+	cut := func(b []byte, truncated *bool) []byte {
+		if maxBody > 0 && len(b) > maxBody {
+			*truncated = true
+			b = b[:maxBody]
+		}
+		return append([]byte(nil), b...)
+	}
+	return func(next Handler) Handler {
+		return func(ex *Exchange) error {
+			start := time.Now()
+			err := next(ex)
+
+			c := Capture{
+				Method:   ex.Request.Method,
+				Path:     ex.Path,
+				TraceId:  ex.Request.Header.Get("traceId"),
+				Duration: time.Since(start),
+				Err:      err,
+			}
+			if ex.Response != nil {
+				c.StatusCode = ex.Response.StatusCode
+			}
+			c.RequestBody = cut(ex.RequestBody, &c.Truncated)
+			c.ResponseBody = cut(ex.ResponseBody, &c.Truncated)
+			sink(c)
+			return err
+		}
+	}
+}
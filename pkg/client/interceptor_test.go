@@ -0,0 +1,135 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_Interceptors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	lookup := func(string) (string, error) { return "testAppSecret", nil }
+	mux.Handle("/api/data", VerifyMiddleware(lookup, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Tenant") != "t1" {
+			t.Errorf("tenant header missing: %v", r.Header)
+		}
+		if r.URL.Query().Get("fail") != "" {
+			w.Write([]byte(`{"code":1001,"message":"bad song","success":false}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"success":true,"data":{"foo":"bar"}}`))
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(next Handler) Handler {
+			return func(ex *Exchange) error {
+				order = append(order, name+">")
+				err := next(ex)
+				order = append(order, "<"+name)
+				return err
+			}
+		}
+	}
+	var logs []string
+	var captures []Capture
+	var sawEnvelope *BaseResponse
+
+	tenant := http.Header{}
+	tenant.Set("X-Tenant", "t1")
+	tenant.Set("sign", "forged") // must not override the real signature
+
+	client := NewClient("testAppId", "testAppSecret", server.URL,
+		WithInterceptors(trace("a"), trace("b")),
+		WithInterceptors(
+			HeaderInterceptor(tenant),
+			LoggingInterceptor(func(format string, args ...interface{}) { logs = append(logs, fmt.Sprintf(format, args...)) }),
+			BodyCaptureInterceptor(8, func(c Capture) { captures = append(captures, c) }),
+			func(next Handler) Handler {
+				return func(ex *Exchange) error {
+					if ex.Request.Header.Get("sign") == "" {
+						t.Error("interceptor saw an unsigned request")
+					}
+					err := next(ex)
+					sawEnvelope = ex.Envelope
+					return err
+				}
+			},
+		),
+	)
+
+	var result struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := client.Do("POST", "/api/data", nil, map[string]string{"songId": "123456"}, &result); err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	if result.Data["foo"] != "bar" {
+		t.Errorf("unexpected result %v", result)
+	}
+	if got := strings.Join(order, " "); got != "a> b> <b <a" {
+		t.Errorf("Expected a outermost, got %s", got)
+	}
+	if sawEnvelope == nil || !sawEnvelope.Success {
+		t.Errorf("interceptor did not see decoded envelope: %+v", sawEnvelope)
+	}
+	if len(logs) != 1 || !strings.HasPrefix(logs[0], "yinsuda: POST /api/data status=200 code=0 traceId=musician-openapi_") {
+		t.Errorf("unexpected log %q", logs)
+	}
+	c := captures[0]
+	if string(c.RequestBody) != `{"songId` || string(c.ResponseBody) != `{"code":` || !c.Truncated || c.StatusCode != 200 {
+		t.Errorf("unexpected capture %+v", c)
+	}
+
+	// Business errors pass through the chain.
+	err := client.Do("POST", "/api/data", map[string][]string{"fail": {"1"}}, nil, &result)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 1001 {
+		t.Fatalf("Expected APIError 1001, got %v", err)
+	}
+	if !strings.Contains(logs[1], "code=1001") || !strings.Contains(logs[1], "err=") || captures[1].Err != err {
+		t.Errorf("error not seen by interceptors: %q %+v", logs[1], captures[1])
+	}
+
+	// An interceptor may answer without sending.
+	denied := errors.New("denied by policy")
+	blocked := NewClient("testAppId", "testAppSecret", server.URL, WithInterceptors(func(Handler) Handler {
+		return func(*Exchange) error { return denied }
+	}))
+	if err := blocked.Do("POST", "/api/data", nil, nil, &result); err != denied {
+		t.Errorf("Expected denied, got %v", err)
+	}
+}
+
+func TestLoggingInterceptor_Redacts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var logs []string
+	leak := fmt.Errorf("Post %q: connection reset", "https://api.example.com/data?accessToken=tok&sign=abc")
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithInterceptors(
+		LoggingInterceptor(func(format string, args ...interface{}) { logs = append(logs, fmt.Sprintf(format, args...)) }),
+		func(Handler) Handler {
+			return func(*Exchange) error { return leak }
+		},
+	))
+	if err := client.Do("POST", "/api/data", nil, nil, nil); err != leak {
+		t.Fatalf("Expected the interceptor error, got %v", err)
+	}
+	if len(logs) != 1 || strings.Contains(logs[0], "accessToken=") || strings.Contains(logs[0], "sign=") || !strings.Contains(logs[0], "err=Post \"[redacted-url]\"") {
+		t.Errorf("error not redacted: %q", logs)
+	}
+}
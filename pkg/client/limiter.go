@@ -0,0 +1,330 @@
+package client
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LimitConfig configures a Limiter. Zero fields disable the matching limit.
+type LimitConfig struct {
+	// QPS and Burst bound the overall request rate. Burst defaults to
+	// ceil(QPS), at least 1.
+	QPS   float64
+	Burst int
+	// PathQPS bounds individual endpoint paths, e.g. "/mcrc-sas/yinsuda/getSongList".
+	// DefaultPathQPS applies to every path not listed. Path buckets have a
+	// burst of ceil(rate), at least 1.
+	PathQPS        map[string]float64
+	DefaultPathQPS float64
+	// MaxInFlight caps concurrent requests.
+	MaxInFlight int
+	// ThrottleCodes are business codes that mean "slow down", in addition
+	// to HTTP 429.
+	ThrottleCodes []int
+	// MinRateFactor is the lowest fraction of its configured rate a bucket
+	// is cut to when throttled. Defaults to 0.1.
+	MinRateFactor float64
+	// RecoverAfter is how long a bucket must go without throttling before
+	// each step back towards its configured rate. Defaults to 10s.
+	RecoverAfter time.Duration
+}
+
+// Limiter enforces LimitConfig for one or more Clients. It adapts to
+// throttling: every throttled response halves the rate of the overall and
+// path buckets (down to MinRateFactor) and honours Retry-After; the rate is
+// doubled back after each quiet RecoverAfter period.
+type Limiter struct {
+	cfg LimitConfig
+
+	lock      sync.Mutex
+	global    *bucket
+	paths     map[string]*bucket
+	inFlight  int
+	waiting   int
+	throttled uint64
+	slots     chan struct{}
+	now       func() time.Time
+}
+
+// NewLimiter returns a Limiter for cfg.
+func NewLimiter(cfg LimitConfig) *Limiter {
+	if cfg.MinRateFactor <= 0 || cfg.MinRateFactor > 1 {
+		cfg.MinRateFactor = 0.1
+	}
+	if cfg.RecoverAfter <= 0 {
+		cfg.RecoverAfter = 10 * time.Second
+	}
+	l := &Limiter{
+		cfg:   cfg,
+		paths: make(map[string]*bucket),
+		now:   time.Now,
+	}
+	if cfg.QPS > 0 {
+		burst := cfg.Burst
+		if burst <= 0 {
+			burst = defaultBurst(cfg.QPS)
+		}
+		l.global = newBucket(cfg.QPS, burst, l.now())
+	}
+	if cfg.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return l
+}
+
+func defaultBurst(rate float64) int {
+	return int(math.Max(1, math.Ceil(rate)))
+}
+
+// Wait blocks until a request to path may start, or ctx is done. On success
+// the caller must call release once the request has finished.
+func (l *Limiter) Wait(ctx context.Context, path string) (release func(), err error) {
+	l.lock.Lock()
+	l.waiting++
+	l.lock.Unlock()
+	defer func() {
+		l.lock.Lock()
+		l.waiting--
+		l.lock.Unlock()
+	}()
+
+	// 1. In-flight slot first, so queued requests don't hold rate tokens.
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		l.lock.Lock()
+		l.inFlight--
+		l.lock.Unlock()
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	// 2. Overall and path tokens
+	for {
+		wait := l.reserve(path)
+		if wait <= 0 {
+			break
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if l.slots != nil {
+				<-l.slots
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	l.lock.Lock()
+	l.inFlight++
+	l.lock.Unlock()
+	return release, nil
+}
+
+// reserve takes one token from every bucket that applies to path, or
+// returns how long to wait before trying again without taking any.
+func (l *Limiter) reserve(path string) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	buckets := make([]*bucket, 0, 2)
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+	if b := l.pathBucket(path, now); b != nil {
+		buckets = append(buckets, b)
+	}
+
+	var wait time.Duration
+	for _, b := range buckets {
+		b.refill(now, l.cfg)
+		if w := b.wait(now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return 0
+}
+
+// pathBucket returns the bucket for path, creating it on first use. It is
+// nil when path has no limit. Callers hold l.lock.
+func (l *Limiter) pathBucket(path string, now time.Time) *bucket {
+	if b, ok := l.paths[path]; ok {
+		return b
+	}
+	rate, ok := l.cfg.PathQPS[path]
+	if !ok {
+		rate = l.cfg.DefaultPathQPS
+	}
+	if rate <= 0 {
+		return nil
+	}
+	b := newBucket(rate, defaultBurst(rate), now)
+	l.paths[path] = b
+	return b
+}
+
+// Observe feeds a response back to the limiter. statusCode is the HTTP
+// status, code the business code (0 if unknown) and retryAfter the parsed
+// Retry-After header (0 if absent). It reports whether the response was
+// treated as throttling.
+func (l *Limiter) Observe(path string, statusCode, code int, retryAfter time.Duration) bool {
+	if !l.isThrottle(statusCode, code) {
+		return false
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.throttled++
+	now := l.now()
+	for _, b := range []*bucket{l.global, l.pathBucket(path, now)} {
+		if b == nil {
+			continue
+		}
+		b.refill(now, l.cfg)
+		b.rate = math.Max(b.rate/2, b.baseRate*l.cfg.MinRateFactor)
+		b.lastThrottle = now
+		if until := now.Add(retryAfter); retryAfter > 0 && until.After(b.pausedUntil) {
+			b.pausedUntil = until
+		}
+	}
+	return true
+}
+
+func (l *Limiter) isThrottle(statusCode, code int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if code == 0 {
+		return false
+	}
+	for _, c := range l.cfg.ThrottleCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// LimiterState is a point-in-time view of a Limiter, for metrics.
+type LimiterState struct {
+	InFlight    int
+	MaxInFlight int
+	Waiting     int    // callers blocked in Wait
+	Throttled   uint64 // throttled responses observed so far
+	Global      *BucketState
+	Paths       map[string]BucketState
+}
+
+// BucketState describes one token bucket.
+type BucketState struct {
+	BaseRate    float64 // configured requests per second
+	Rate        float64 // current rate after throttling
+	Burst       int
+	Tokens      float64
+	PausedUntil time.Time // set from Retry-After; zero if not paused
+}
+
+// State returns the current limiter state.
+func (l *Limiter) State() LimiterState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	s := LimiterState{
+		InFlight:    l.inFlight,
+		MaxInFlight: l.cfg.MaxInFlight,
+		Waiting:     l.waiting,
+		Throttled:   l.throttled,
+		Paths:       make(map[string]BucketState, len(l.paths)),
+	}
+	if l.global != nil {
+		l.global.refill(now, l.cfg)
+		gs := l.global.state(now)
+		s.Global = &gs
+	}
+	for path, b := range l.paths {
+		b.refill(now, l.cfg)
+		s.Paths[path] = b.state(now)
+	}
+	return s
+}
+
+type bucket struct {
+	baseRate     float64
+	rate         float64
+	burst        int
+	tokens       float64
+	last         time.Time
+	lastThrottle time.Time
+	pausedUntil  time.Time
+}
+
+func newBucket(rate float64, burst int, now time.Time) *bucket {
+	return &bucket{baseRate: rate, rate: rate, burst: burst, tokens: float64(burst), last: now}
+}
+
+// refill adds the tokens earned since the last call and recovers the rate
+// one doubling per quiet RecoverAfter period.
+func (b *bucket) refill(now time.Time, cfg LimitConfig) {
+	if now.After(b.last) {
+		b.tokens = math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	for b.rate < b.baseRate && now.Sub(b.lastThrottle) >= cfg.RecoverAfter {
+		b.rate = math.Min(b.baseRate, b.rate*2)
+		b.lastThrottle = b.lastThrottle.Add(cfg.RecoverAfter)
+	}
+}
+
+// wait returns how long until a token is available; 0 if one is now.
+func (b *bucket) wait(now time.Time) time.Duration {
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) state(now time.Time) BucketState {
+	s := BucketState{BaseRate: b.baseRate, Rate: b.rate, Burst: b.burst, Tokens: b.tokens}
+	if now.Before(b.pausedUntil) {
+		s.PausedUntil = b.pausedUntil
+	}
+	return s
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Buckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(LimitConfig{
+		QPS:     10,
+		Burst:   2,
+		PathQPS: map[string]float64{"/slow": 1},
+	})
+	l.now = func() time.Time { return now }
+	l.global.last = now
+
+	// Burst of 2 overall, then 100ms per token.
+	if w := l.reserve("/a"); w != 0 {
+		t.Fatalf("first reserve waited %v", w)
+	}
+	if w := l.reserve("/a"); w != 0 {
+		t.Fatalf("second reserve waited %v", w)
+	}
+	if w := l.reserve("/a"); w != 100*time.Millisecond {
+		t.Fatalf("Expected 100ms wait, got %v", w)
+	}
+
+	// /slow allows one request per second on top of the overall limit.
+	now = now.Add(time.Second)
+	if w := l.reserve("/slow"); w != 0 {
+		t.Fatalf("/slow reserve waited %v", w)
+	}
+	if w := l.reserve("/slow"); w != time.Second {
+		t.Fatalf("Expected 1s wait on /slow, got %v", w)
+	}
+	// A path without its own limit is unaffected.
+	if w := l.reserve("/a"); w != 0 {
+		t.Fatalf("/a blocked by /slow: %v", w)
+	}
+
+	s := l.State()
+	if s.Global == nil || s.Global.BaseRate != 10 || len(s.Paths) != 1 || s.Paths["/slow"].BaseRate != 1 {
+		t.Errorf("unexpected state %+v", s)
+	}
+}
+
+func TestLimiter_Throttle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(LimitConfig{QPS: 8, ThrottleCodes: []int{10429}, RecoverAfter: 10 * time.Second})
+	l.now = func() time.Time { return now }
+	l.global.last = now
+
+	if l.Observe("/a", 200, 0, 0) || l.Observe("/a", 200, 500, 0) {
+		t.Fatal("non-throttle responses treated as throttling")
+	}
+	if !l.Observe("/a", 429, 0, 0) {
+		t.Fatal("HTTP 429 not treated as throttling")
+	}
+	if !l.Observe("/a", 200, 10429, 3*time.Second) {
+		t.Fatal("throttle code not treated as throttling")
+	}
+
+	s := l.State()
+	if s.Throttled != 2 || s.Global.Rate != 2 {
+		t.Fatalf("Expected rate 2 after two throttles, got %+v", s.Global)
+	}
+	if !s.Global.PausedUntil.Equal(now.Add(3 * time.Second)) {
+		t.Errorf("Retry-After not honoured: %v", s.Global.PausedUntil)
+	}
+	if w := l.reserve("/a"); w != 3*time.Second {
+		t.Errorf("Expected 3s pause, got %v", w)
+	}
+
+	// Rate is floored at MinRateFactor of the configured rate.
+	for i := 0; i < 10; i++ {
+		l.Observe("/a", 429, 0, 0)
+	}
+	if r := l.State().Global.Rate; r != 0.8 {
+		t.Errorf("Expected rate floor 0.8, got %v", r)
+	}
+
+	// Each quiet RecoverAfter period doubles the rate back.
+	now = now.Add(10 * time.Second)
+	if r := l.State().Global.Rate; r != 1.6 {
+		t.Errorf("Expected 1.6 after one recovery step, got %v", r)
+	}
+	now = now.Add(time.Minute)
+	if r := l.State().Global.Rate; r != 8 {
+		t.Errorf("Expected full recovery to 8, got %v", r)
+	}
+}
+
+func TestLimiter_WaitContext(t *testing.T) {
+	l := NewLimiter(LimitConfig{QPS: 0.1, MaxInFlight: 1})
+
+	release, err := l.Wait(context.Background(), "/a")
+	if err != nil {
+		t.Fatalf("first Wait failed: %v", err)
+	}
+
+	// Blocked on the in-flight cap.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, "/a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded on in-flight cap, got %v", err)
+	}
+	if s := l.State(); s.InFlight != 1 || s.Waiting != 0 {
+		t.Errorf("unexpected state %+v", s)
+	}
+	release()
+
+	// Slot free now, but the bucket is empty for 10s.
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, "/a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded on rate limit, got %v", err)
+	}
+	if s := l.State(); s.InFlight != 0 || len(l.slots) != 0 {
+		t.Errorf("slot leaked after cancelled Wait: %+v", s)
+	}
+}
+
+func TestClient_WithLimiter(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			json.NewEncoder(w).Encode(BaseResponse{Code: 10429, Message: "too many requests"})
+		default:
+			json.NewEncoder(w).Encode(BaseResponse{Code: 0, Success: true})
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	l := NewLimiter(LimitConfig{QPS: 100, MaxInFlight: 4, ThrottleCodes: []int{10429}})
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithLimiter(l))
+
+	var apiErr *APIError
+	if err := client.Do("GET", "/api/data", nil, nil, &BaseResponse{}); !errors.As(err, &apiErr) || apiErr.StatusCode != 429 {
+		t.Fatalf("Expected 429 APIError, got %v", err)
+	}
+	if s := l.State(); s.Throttled != 1 || s.Global.PausedUntil.IsZero() {
+		t.Fatalf("429 not observed: %+v", s)
+	}
+
+	// The Retry-After pause outlasts this deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.DoContext(ctx, "GET", "/api/data", nil, nil, &BaseResponse{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded while paused, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("request sent during Retry-After pause (%d calls)", n)
+	}
+
+	if err := client.Do("GET", "/api/data", nil, nil, &BaseResponse{}); !errors.As(err, &apiErr) || apiErr.Code != 10429 {
+		t.Fatalf("Expected business throttle error, got %v", err)
+	}
+	if s := l.State(); s.Throttled != 2 || s.InFlight != 0 || s.Global.Rate != 25 {
+		t.Errorf("unexpected state after business throttle: %+v", s.Global)
+	}
+}
+
+func TestClient_EndpointContext(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(BaseResponse{Code: 0, Success: true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithLimiter(NewLimiter(LimitConfig{QPS: 0.1})))
+	if _, err := client.GetSongListContext(context.Background(), &GetSongListRequest{}); err != nil {
+		t.Fatalf("GetSongListContext failed: %v", err)
+	}
+
+	// The bucket is empty for 10s; each endpoint gives up when ctx does.
+	endpoints := []func(ctx context.Context) error{
+		func(ctx context.Context) error {
+			_, err := client.GetSongListContext(ctx, &GetSongListRequest{})
+			return err
+		},
+		func(ctx context.Context) error { _, err := client.GetSongInfoContext(ctx, []string{"1"}); return err },
+		func(ctx context.Context) error {
+			_, err := client.GetSongUrlContext(ctx, &GetSongUrlRequest{SongId: "1"})
+			return err
+		},
+		func(ctx context.Context) error {
+			_, err := client.SearchSongContext(ctx, &SearchSongRequest{SearchText: "a", SearchType: SearchTypeFull, Limit: 10})
+			return err
+		},
+		func(ctx context.Context) error {
+			_, err := client.QuerySongListPageContext(ctx, &PageRequest{Length: 1})
+			return err
+		},
+		func(ctx context.Context) error { _, err := client.QuerySongListDetailContext(ctx, "c"); return err },
+		func(ctx context.Context) error {
+			_, err := client.QueryRankingListPageContext(ctx, &PageRequest{Length: 1})
+			return err
+		},
+		func(ctx context.Context) error { _, err := client.QueryRankingListDetailContext(ctx, "c"); return err },
+	}
+	for i, call := range endpoints {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		err := call(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("call %d: Expected DeadlineExceeded, got %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 request sent, got %d", n)
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// maxLoggedError bounds error text in logs; APIError embeds the whole body.
+const maxLoggedError = 512
+
+var (
+	// Secret-bearing JSON fields, e.g. "accessToken":"...".
+	secretFieldPattern = regexp.MustCompile(`("(?:appSecret|accessToken|sign|url)"\s*:\s*)"[^"]*"`)
+	// Any remaining http(s) URL, e.g. a media URL in free text.
+	urlPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+)
+
+// Redact masks appSecret, accessToken, sign and url values in JSON text and
+// any http(s) URL, so response bodies and error messages can be logged.
+func Redact(s string) string {
+	s = secretFieldPattern.ReplaceAllString(s, `$1"[redacted]"`)
+	return urlPattern.ReplaceAllString(s, "[redacted-url]")
+}
+
+// errorAttr logs err redacted and truncated.
+func errorAttr(err error) slog.Attr {
+	return slog.String("error", loggedError(err))
+}
+
+// loggedError returns the text of err redacted and truncated for logs.
+func loggedError(err error) string {
+	text := Redact(err.Error())
+	if len(text) > maxLoggedError {
+		text = text[:maxLoggedError] + "...(truncated)"
+	}
+	return text
+}
+
+// logRequest logs a completed exchange. Success is logged at Debug, business
+// errors at Warn and HTTP or transport failures at Error.
+func (c *Client) logRequest(ctx context.Context, ex *Exchange, start time.Time, err error) {
+	if c.logger == nil {
+		return
+	}
+	level := slog.LevelDebug
+	msg := "yinsuda request"
+	var apiErr *APIError
+	switch {
+	case err == nil:
+	case errors.As(err, &apiErr) && apiErr.StatusCode == 200:
+		level, msg = slog.LevelWarn, "yinsuda request rejected"
+	default:
+		level, msg = slog.LevelError, "yinsuda request failed"
+	}
+	if !c.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", ex.Request.Method),
+		slog.String("path", ex.Path),
+		slog.String("traceId", ex.Request.Header.Get("traceId")),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if ex.Response != nil {
+		attrs = append(attrs, slog.Int("status", ex.Response.StatusCode))
+	}
+	if ex.Envelope != nil {
+		attrs = append(attrs, slog.Int("code", ex.Envelope.Code))
+	}
+	if err != nil {
+		attrs = append(attrs, errorAttr(err))
+	}
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	in := `{"accessToken":"abc","appSecret" : "s3cr3t","sign":"d9369","data":{"mediaList":[{"url":"http://cdn.example.com/1.mp3?k=v"}]},"msg":"see https://x.example.com/a"}`
+	want := `{"accessToken":"[redacted]","appSecret" : "[redacted]","sign":"[redacted]","data":{"mediaList":[{"url":"[redacted]"}]},"msg":"see [redacted-url]"}`
+	if got := Redact(in); got != want {
+		t.Errorf("\nExpected: %s\nActual:   %s", want, got)
+	}
+}
+
+func TestClient_WithLogger(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok-SECRET", Expire: 900}})
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"success":true,"data":{"mediaList":[{"url":"http://cdn.example.com/1.mp3"}]}}`))
+	})
+	mux.HandleFunc("/rejected", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":1001,"message":"bad","success":false,"data":{"url":"http://cdn.example.com/2.mp3"}}`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(strings.Repeat("x", 2000)))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithLogger(logger))
+
+	var result BaseResponse
+	client.Do("POST", "/ok", nil, nil, &result)
+	client.Do("POST", "/rejected", nil, nil, &result)
+	client.Do("POST", "/broken", nil, nil, &result)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 log records, got %d:\n%s", len(records), buf.String())
+	}
+
+	token := records[0]
+	if token["msg"] != "yinsuda token refreshed" || token["level"] != "INFO" || token["expireSeconds"] != float64(900) || token["expiresAt"] == nil {
+		t.Errorf("unexpected token record %v", token)
+	}
+
+	cases := []struct {
+		path, level string
+		status      float64
+		code        interface{}
+	}{
+		{"/ok", "DEBUG", 200, float64(0)},
+		{"/rejected", "WARN", 200, float64(1001)},
+		{"/broken", "ERROR", 502, nil},
+	}
+	for i, tc := range cases {
+		rec := records[i+1]
+		if rec["path"] != tc.path || rec["level"] != tc.level || rec["status"] != tc.status || rec["code"] != tc.code {
+			t.Errorf("%s: unexpected record %v", tc.path, rec)
+		}
+		if !strings.HasPrefix(rec["traceId"].(string), "musician-openapi_") || rec["latency"] == nil {
+			t.Errorf("%s: missing traceId or latency: %v", tc.path, rec)
+		}
+	}
+	if e := records[3]["error"].(string); len(e) > maxLoggedError+len("...(truncated)") {
+		t.Errorf("error not truncated: %d bytes", len(e))
+	}
+
+	for _, secret := range []string{"tok-SECRET", "testAppSecret", "cdn.example.com"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("log leaks %q:\n%s", secret, buf.String())
+		}
+	}
+}
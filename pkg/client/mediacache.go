@@ -0,0 +1,149 @@
+package client
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MediaCache holds GetSongUrl responses for use while the API is unavailable.
+type MediaCache interface {
+	Get(songId, identityId string) (*GetSongUrlResponse, bool)
+	Put(songId, identityId string, resp *GetSongUrlResponse)
+}
+
+// DefaultMediaCacheEntries is the bound of a MemoryMediaCache whose
+// MaxEntries is 0.
+const DefaultMediaCacheEntries = 10000
+
+// MemoryMediaCache is an in-process MediaCache. The zero value is ready to
+// use.
+//
+// An entry is not returned, and is removed when looked up, once it is older
+// than MaxAge (zero keeps it until expiry) or once the earliest Expire of its
+// media has passed, as the signed URLs no longer work then. Beyond
+// MaxEntries the least recently used entry is evicted.
+type MemoryMediaCache struct {
+	MaxAge time.Duration
+	// MaxEntries bounds the cache. 0 means DefaultMediaCacheEntries; < 0
+	// removes the bound.
+	MaxEntries int
+
+	lock    sync.Mutex
+	entries map[string]*list.Element // of *mediaEntry
+	lru     *list.List               // front is most recently used
+	now     func() time.Time
+}
+
+type mediaEntry struct {
+	key       string
+	resp      *GetSongUrlResponse
+	expiresAt time.Time // zero if neither MaxAge nor the media bound it
+}
+
+func NewMemoryMediaCache(maxAge time.Duration) *MemoryMediaCache {
+	return &MemoryMediaCache{MaxAge: maxAge}
+}
+
+// init allocates the zero value's state. Callers hold m.lock.
+func (m *MemoryMediaCache) init() {
+	if m.entries == nil {
+		m.entries = make(map[string]*list.Element)
+		m.lru = list.New()
+	}
+	if m.now == nil {
+		m.now = time.Now
+	}
+}
+
+func (m *MemoryMediaCache) Get(songId, identityId string) (*GetSongUrlResponse, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	el, ok := m.entries[songId+"\x00"+identityId]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*mediaEntry)
+	if !e.expiresAt.IsZero() && !m.now().Before(e.expiresAt) {
+		m.remove(el)
+		return nil, false
+	}
+	m.lru.MoveToFront(el)
+	return e.resp, true
+}
+
+func (m *MemoryMediaCache) Put(songId, identityId string, resp *GetSongUrlResponse) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	key := songId + "\x00" + identityId
+	e := &mediaEntry{key: key, resp: resp, expiresAt: m.expiresAt(resp)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.lru.MoveToFront(el)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(e)
+	limit := m.MaxEntries
+	if limit == 0 {
+		limit = DefaultMediaCacheEntries
+	}
+	for limit > 0 && m.lru.Len() > limit {
+		m.remove(m.lru.Back())
+	}
+}
+
+// expiresAt is when an entry for resp stored now stops being served: after
+// MaxAge, or at the earliest media Expire, whichever comes first.
+func (m *MemoryMediaCache) expiresAt(resp *GetSongUrlResponse) time.Time {
+	var at time.Time
+	if m.MaxAge > 0 {
+		at = m.now().Add(m.MaxAge)
+	}
+	if resp == nil {
+		return at
+	}
+	for _, media := range resp.MediaList {
+		if exp, ok := parseMediaExpire(media.Expire); ok && (at.IsZero() || exp.Before(at)) {
+			at = exp
+		}
+	}
+	return at
+}
+
+// parseMediaExpire reads MediaInfo.Expire, a Unix timestamp in seconds or
+// milliseconds or a "2006-01-02 15:04:05" local time. Other values are
+// ignored.
+func parseMediaExpire(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n), true
+		}
+		return time.Unix(n, 0), true
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Len returns the number of entries held, expired ones included until they
+// are looked up or evicted.
+func (m *MemoryMediaCache) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.init()
+	return m.lru.Len()
+}
+
+func (m *MemoryMediaCache) remove(el *list.Element) {
+	m.lru.Remove(el)
+	delete(m.entries, el.Value.(*mediaEntry).key)
+}
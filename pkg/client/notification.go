@@ -1,5 +1,11 @@
 package client
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
 // NotificationType constants
 const (
 	NotifyTypeSong        = "SONG"
@@ -19,6 +25,22 @@ type Notification struct {
 	Codes      []string     `json:"codes,omitempty"` // For SONG_LIST/RANKING_LIST type
 }
 
+// AppIdString returns AppId as a string, whether the platform sent it as a
+// JSON string or number. It returns "" when AppId is missing.
+func (n *Notification) AppIdString() string {
+	switch v := n.AppId.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(n.AppId)
+}
+
 type SongChange struct {
 	SongId     string `json:"songId"`
 	ChangeDate string `json:"changeDate"`
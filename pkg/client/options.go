@@ -0,0 +1,190 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Option configures a Client. Pass options to NewClient.
+type Option func(*Client)
+
+// WithSigner sets the signing scheme used by Do. Defaults to MD5Signer.
+func WithSigner(s Signer) Option {
+	return func(c *Client) {
+		c.signer = s
+	}
+}
+
+// WithSignTraceLog makes Do log a redacted SignTrace through logf whenever
+// the API rejects a request with what looks like a signature error (see
+// IsSignError). Only the md5 scheme is traced. log.Printf fits logf.
+func WithSignTraceLog(logf func(format string, args ...interface{})) Option {
+	return func(c *Client) {
+		c.signTraceLog = logf
+	}
+}
+
+// WithHTTPClient makes the client, and its TokenProvider, send requests
+// through hc. Defaults to an *http.Client with a 30s timeout. Sharing one hc
+// between clients shares its connection pool; see ClientPool.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+		c.tokenProvider.httpClient = hc
+	}
+}
+
+// WithQueryEncoder sets how Do encodes query parameters. The same encoding
+// is used in the URL and in the signature. Defaults to the zero QueryEncoder,
+// which matches url.Values.Encode.
+func WithQueryEncoder(e QueryEncoder) Option {
+	return func(c *Client) {
+		c.queryEncoder = e
+	}
+}
+
+// WithLimiter makes Do wait on l before each request and report throttled
+// responses back to it. A Limiter may be shared by several Clients to
+// enforce one budget across them.
+func WithLimiter(l *Limiter) Option {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// WithBreaker makes Do fail fast with a *BreakerOpenError while the circuit
+// for a path is open. A Breaker may be shared by several Clients.
+func WithBreaker(b *Breaker) Option {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
+
+// WithMediaCache makes GetSongUrl cache its responses and fall back to them
+// while the breaker is open. See GetSongUrl.
+func WithMediaCache(m MediaCache) Option {
+	return func(c *Client) {
+		c.mediaCache = m
+	}
+}
+
+// WithInterceptors adds interceptors around every request sent by Do. The
+// first one given is the outermost. The option may be repeated; later
+// interceptors are nested inside earlier ones.
+func WithInterceptors(in ...Interceptor) Option {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, in...)
+	}
+}
+
+// WithLogger makes Do and the client's TokenProvider log through l. Each
+// request is logged with its path, traceId, latency, HTTP status and business
+// code: at Debug on success, Warn when the API rejects it and Error when it
+// fails outright. appSecret, accessToken, sign and media URLs are redacted.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+		c.tokenProvider.SetLogger(l)
+	}
+}
+
+// WithTelemetry makes Do and the client's TokenProvider report spans to t
+// and measurements to m. Each Do call gets one span carrying AttrPath,
+// AttrTraceId, AttrResponseTraceId, AttrCode and AttrRetryCount; token
+// fetches get a span of their own. Either argument may be nil to keep what
+// is already installed, so WithTelemetry(t, nil) and WithTelemetry(nil, m)
+// compose. Non-nil Metrics replace the current ones; to report to several,
+// use WithAddedMetrics.
+func WithTelemetry(t Tracer, m Metrics) Option {
+	return func(c *Client) {
+		if t != nil {
+			c.tracer = t
+			c.tokenProvider.SetTracer(t)
+		}
+		if m != nil {
+			c.metrics = m
+			c.tokenProvider.SetMetrics(m)
+		}
+	}
+}
+
+// WithAddedMetrics makes Do and the client's TokenProvider report to m as
+// well as to the Metrics already installed, so exporters such as
+// promcollector and an OpenTelemetry adapter can be used together. A later
+// WithTelemetry with non-nil Metrics replaces them all.
+func WithAddedMetrics(m Metrics) Option {
+	return func(c *Client) {
+		c.metrics = MultiMetrics(c.metrics, m)
+		c.tokenProvider.lock.RLock()
+		current := c.tokenProvider.metrics
+		c.tokenProvider.lock.RUnlock()
+		c.tokenProvider.SetMetrics(MultiMetrics(current, m))
+	}
+}
+
+// WithMaxResponseSize caps how many bytes of a response body Do reads.
+// Larger responses fail with a *ResponseTooLargeError. Defaults to
+// DefaultMaxResponseSize; n <= 0 removes the limit. Streamed responses
+// (StreamSongList, StreamSearchSong) use WithMaxStreamSize instead.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) {
+		c.maxResponseSize = n
+	}
+}
+
+// WithMaxStreamSize caps how many bytes of a streamed response are read.
+// Streamed songs are passed on as they are decoded, so memory stays flat
+// whatever the size; by default there is no limit. n <= 0 removes it.
+func WithMaxStreamSize(n int64) Option {
+	return func(c *Client) {
+		c.maxStreamSize = n
+	}
+}
+
+// WithMaxErrorBody caps how much of a response body is kept in an
+// *APIError or *NonJSONError, and so in its message. Defaults to
+// DefaultMaxErrorBody; n <= 0 keeps the whole body.
+func WithMaxErrorBody(n int) Option {
+	return func(c *Client) {
+		c.maxErrorBody = n
+	}
+}
+
+// WithValidation turns the Validate check that endpoint methods and Call run
+// before sending on or off. It is on by default; turn it off to let the
+// server judge requests this package considers invalid.
+func WithValidation(enabled bool) Option {
+	return func(c *Client) {
+		c.validate = enabled
+	}
+}
+
+// WithMaxSongInfoIds makes GetSongInfo fail with a *ValidationError,
+// without sending, when asked for more than n ids. The API reference does
+// not document a batch limit, so by default none is enforced and the server
+// decides; set n to the limit agreed for your appId. n <= 0 removes it.
+// It has no effect with WithValidation(false).
+func WithMaxSongInfoIds(n int) Option {
+	return func(c *Client) {
+		c.maxSongInfoIds = n
+	}
+}
+
+// WithMaxSearchLimit makes SearchSong fail with a *ValidationError, without
+// sending, when Limit is above n. Like the GetSongInfo batch size, the API
+// reference documents no maximum, so none is enforced by default. n <= 0
+// removes it. It has no effect with WithValidation(false).
+func WithMaxSearchLimit(n int) Option {
+	return func(c *Client) {
+		c.maxSearchLimit = n
+	}
+}
+
+// WithRequestDefaults sets the values endpoint methods send for zero request
+// fields. A zero SongListLimit keeps the built-in default; the search fields
+// apply only when set. See RequestDefaults.
+func WithRequestDefaults(d RequestDefaults) Option {
+	return func(c *Client) {
+		c.defaults = d
+	}
+}
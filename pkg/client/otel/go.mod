@@ -0,0 +1,12 @@
+module github.com/leychan/yinsuda-music/pkg/client/otel
+
+go 1.21
+
+require (
+	github.com/leychan/yinsuda-music v0.0.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/metric v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require github.com/google/uuid v1.6.0 // indirect
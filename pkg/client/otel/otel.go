@@ -0,0 +1,188 @@
+// Package otel adapts OpenTelemetry tracers and meters to the client's
+// Tracer and Metrics hooks. It is a module of its own so the client does
+// not depend on OpenTelemetry.
+//
+//	tel, err := otel.New(otelapi.Tracer("yinsuda"), otelapi.Meter("yinsuda"))
+//	if err != nil {
+//		return err
+//	}
+//	c := client.NewClient(appId, appSecret, baseUrl, tel.Option())
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Instrument names.
+const (
+	MetricRequestDuration = "yinsuda.client.request.duration"
+	MetricRequestErrors   = "yinsuda.client.request.errors"
+	MetricTokenDuration   = "yinsuda.client.token.duration"
+	MetricTokenFailures   = "yinsuda.client.token.failures"
+	MetricCacheLookups    = "yinsuda.client.cache.lookups"
+)
+
+// Telemetry holds a client.Tracer and client.Metrics backed by
+// OpenTelemetry. It may be shared by several Clients.
+type Telemetry struct {
+	tracer  client.Tracer
+	metrics client.Metrics
+}
+
+// New returns Telemetry reporting spans to t and instruments created from m.
+// Either may be nil to leave that half to the client's current setting.
+func New(t trace.Tracer, m metric.Meter) (*Telemetry, error) {
+	tel := &Telemetry{}
+	if t != nil {
+		tel.tracer = Tracer(t)
+	}
+	if m != nil {
+		metrics, err := Metrics(m)
+		if err != nil {
+			return nil, err
+		}
+		tel.metrics = metrics
+	}
+	return tel, nil
+}
+
+// Option installs the tracer and metrics with client.WithTelemetry.
+func (tel *Telemetry) Option() client.Option {
+	return client.WithTelemetry(tel.tracer, tel.metrics)
+}
+
+// Tracer adapts t to client.Tracer. Spans are started as client spans.
+func Tracer(t trace.Tracer) client.Tracer {
+	return tracer{t}
+}
+
+type tracer struct{ t trace.Tracer }
+
+func (a tracer) Start(ctx context.Context, name string, attrs ...client.Attribute) (context.Context, client.Span) {
+	ctx, s := a.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(convert(attrs)...))
+	return ctx, span{s}
+}
+
+type span struct{ s trace.Span }
+
+func (a span) SetAttributes(attrs ...client.Attribute) {
+	a.s.SetAttributes(convert(attrs)...)
+}
+
+func (a span) RecordError(err error) {
+	a.s.RecordError(err)
+	a.s.SetStatus(codes.Error, err.Error())
+}
+
+func (a span) End() {
+	a.s.End()
+}
+
+// convert maps client attributes to OTel ones. Values of other types than
+// those client.Attribute documents are recorded as strings.
+func convert(attrs []client.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
+
+type metrics struct {
+	duration      metric.Float64Histogram
+	errors        metric.Int64Counter
+	tokenDuration metric.Float64Histogram
+	tokenFailures metric.Int64Counter
+	cache         metric.Int64Counter
+}
+
+// Metrics creates the client's instruments from m and returns them as
+// client.Metrics:
+//
+//   - yinsuda.client.request.duration (s): per Do call, by yinsuda.path
+//     (client.MetricPath), http.response.status_code and yinsuda.code
+//   - yinsuda.client.request.errors: failed calls, by yinsuda.path and
+//     error.type (client.ErrorClass)
+//   - yinsuda.client.token.duration (s) and yinsuda.client.token.failures:
+//     token fetches, by yinsuda.app_id
+//   - yinsuda.client.cache.lookups: by cache and result (hit or miss)
+func Metrics(m metric.Meter) (client.Metrics, error) {
+	var (
+		mt   metrics
+		err  error
+		errs []error
+	)
+	mt.duration, err = m.Float64Histogram(MetricRequestDuration, metric.WithUnit("s"),
+		metric.WithDescription("API call latency, including limiter waits and token fetches."))
+	errs = append(errs, err)
+	mt.errors, err = m.Int64Counter(MetricRequestErrors,
+		metric.WithDescription("Failed API calls."))
+	errs = append(errs, err)
+	mt.tokenDuration, err = m.Float64Histogram(MetricTokenDuration, metric.WithUnit("s"),
+		metric.WithDescription("Access token fetch latency."))
+	errs = append(errs, err)
+	mt.tokenFailures, err = m.Int64Counter(MetricTokenFailures,
+		metric.WithDescription("Access token fetches that failed."))
+	errs = append(errs, err)
+	mt.cache, err = m.Int64Counter(MetricCacheLookups,
+		metric.WithDescription("Cache lookups by cache and result."))
+	errs = append(errs, err)
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("creating yinsuda instruments: %w", err)
+	}
+	return &mt, nil
+}
+
+func (mt *metrics) RequestDone(ctx context.Context, path string, latency time.Duration, status, code int, err error) {
+	path = client.MetricPath(path)
+	mt.duration.Record(ctx, latency.Seconds(), metric.WithAttributes(
+		attribute.String(client.AttrPath, path),
+		attribute.Int(client.AttrStatusCode, status),
+		attribute.Int(client.AttrCode, code)))
+	if err != nil {
+		mt.errors.Add(ctx, 1, metric.WithAttributes(
+			attribute.String(client.AttrPath, path),
+			attribute.String("error.type", client.ErrorClass(status, code, err))))
+	}
+}
+
+func (mt *metrics) TokenRefreshed(ctx context.Context, appId string, latency time.Duration, err error) {
+	attrs := metric.WithAttributes(attribute.String(client.AttrAppId, appId))
+	mt.tokenDuration.Record(ctx, latency.Seconds(), attrs)
+	if err != nil {
+		mt.tokenFailures.Add(ctx, 1, attrs)
+	}
+}
+
+func (mt *metrics) CacheLookup(ctx context.Context, cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	mt.cache.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache", cache),
+		attribute.String("result", result)))
+}
@@ -0,0 +1,60 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+func TestConvert(t *testing.T) {
+	got := convert([]client.Attribute{
+		{Key: client.AttrPath, Value: "/mcrc-sas/yinsuda/getSongList"},
+		{Key: client.AttrStatusCode, Value: 200},
+		{Key: "n", Value: int64(7)},
+		{Key: "ok", Value: true},
+		{Key: "f", Value: 1.5},
+		{Key: "other", Value: []string{"a"}},
+	})
+	want := []attribute.KeyValue{
+		attribute.String(client.AttrPath, "/mcrc-sas/yinsuda/getSongList"),
+		attribute.Int(client.AttrStatusCode, 200),
+		attribute.Int64("n", 7),
+		attribute.Bool("ok", true),
+		attribute.Float64("f", 1.5),
+		attribute.String("other", "[a]"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d attributes, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("attribute %d: Expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tel, err := New(tracenoop.NewTracerProvider().Tracer("test"), metricnoop.NewMeterProvider().Meter("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := tel.tracer.Start(context.Background(), "yinsuda /x", client.Attribute{Key: client.AttrPath, Value: "/x"})
+	span.SetAttributes(client.Attribute{Key: client.AttrCode, Value: 0})
+	span.RecordError(errors.New("boom"))
+	span.End()
+	tel.metrics.RequestDone(ctx, "/x", time.Millisecond, 502, 0, errors.New("boom"))
+	tel.metrics.TokenRefreshed(ctx, "app", time.Millisecond, nil)
+	tel.metrics.CacheLookup(ctx, "media", true)
+
+	c := client.NewClient("testAppId", "testAppSecret", "http://127.0.0.1:0", tel.Option())
+	if c == nil {
+		t.Fatal("nil client")
+	}
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownApp is returned, wrapped, for an appId the pool has no
+// credentials or notification handler for. CredentialLookup implementations
+// should return it for apps they do not know.
+var ErrUnknownApp = errors.New("unknown appId")
+
+// Credentials are one tenant's API credentials.
+type Credentials struct {
+	AppSecret string
+	BaseUrl   string // overrides PoolConfig.BaseUrl when set
+}
+
+// CredentialLookup returns the current credentials for appId. It is called
+// when a tenant's client is first needed and again whenever the pool checks
+// for rotation, so it may read from a file, a secret store, etc.
+type CredentialLookup func(appId string) (Credentials, error)
+
+// StaticCredentials returns a CredentialLookup over a fixed map.
+func StaticCredentials(creds map[string]Credentials) CredentialLookup {
+	return func(appId string) (Credentials, error) {
+		c, ok := creds[appId]
+		if !ok {
+			return Credentials{}, fmt.Errorf("%w %s", ErrUnknownApp, appId)
+		}
+		return c, nil
+	}
+}
+
+// NotificationHandler handles a notification for one tenant. c is that
+// tenant's client.
+type NotificationHandler func(ctx context.Context, c *Client, n *Notification) error
+
+// PoolConfig configures a ClientPool.
+type PoolConfig struct {
+	BaseUrl string
+	Lookup  CredentialLookup
+	// HTTPClient is shared by every client in the pool, and with it the
+	// transport and its connection pool. Defaults to a client with a 30s
+	// timeout over a clone of http.DefaultTransport that keeps up to 100
+	// idle connections per host, since all tenants talk to the same host.
+	HTTPClient *http.Client
+	// RefreshInterval is how often a tenant's credentials are looked up
+	// again. When they changed, the tenant gets a new client, and a new
+	// token, on its next use. 0 only looks them up again on Refresh.
+	RefreshInterval time.Duration
+	// Options, if set, returns the options for a tenant's client. It is
+	// called each time one is built. Limiters, breakers etc. returned here
+	// are shared by tenants that get the same value.
+	Options func(appId string) []Option
+}
+
+// ClientPool creates and caches one Client per appId, for services that act
+// for several Yinsuda apps. It is safe for concurrent use.
+type ClientPool struct {
+	cfg        PoolConfig
+	httpClient *http.Client
+
+	lock     sync.RWMutex
+	tenants  map[string]*tenant
+	handlers map[string]NotificationHandler
+	now      func() time.Time
+}
+
+// tenant is replaced, never modified, except for checkedAt.
+type tenant struct {
+	client    *Client
+	creds     Credentials
+	checkedAt time.Time
+}
+
+// NewClientPool returns an empty pool; clients are created on first use.
+func NewClientPool(cfg PoolConfig) *ClientPool {
+	hc := cfg.HTTPClient
+	if hc == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = 100
+		hc = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	}
+	return &ClientPool{
+		cfg:        cfg,
+		httpClient: hc,
+		tenants:    make(map[string]*tenant),
+		handlers:   make(map[string]NotificationHandler),
+		now:        time.Now,
+	}
+}
+
+// Client returns the client for appId, creating it from the looked-up
+// credentials on first use. If a rotation check fails the current client is
+// kept until the next check, RefreshInterval later, unless the lookup
+// reports ErrUnknownApp, which removes the tenant.
+func (p *ClientPool) Client(appId string) (*Client, error) {
+	return p.client(appId, false)
+}
+
+func (p *ClientPool) client(appId string, force bool) (*Client, error) {
+	p.lock.RLock()
+	t := p.tenants[appId]
+	fresh := t != nil && !force && (p.cfg.RefreshInterval <= 0 || p.now().Sub(t.checkedAt) < p.cfg.RefreshInterval)
+	p.lock.RUnlock()
+	if fresh {
+		return t.client, nil
+	}
+
+	// Look up outside the lock; concurrent first uses may look up twice,
+	// but only one client is kept.
+	creds, err := p.cfg.Lookup(appId)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	t = p.tenants[appId]
+	if err != nil {
+		if errors.Is(err, ErrUnknownApp) {
+			delete(p.tenants, appId)
+			return nil, err
+		}
+		err = fmt.Errorf("credentials for app %s: %w", appId, err)
+		if t != nil && !force {
+			// Keep serving the current client and wait a full interval
+			// before trying again, so an outage of the credential store
+			// does not put a lookup on every call.
+			t.checkedAt = p.now()
+			return t.client, nil
+		}
+		return nil, err
+	}
+	if t == nil || t.creds != creds {
+		t = &tenant{client: p.newClient(appId, creds), creds: creds}
+		p.tenants[appId] = t
+	}
+	t.checkedAt = p.now()
+	return t.client, nil
+}
+
+func (p *ClientPool) newClient(appId string, creds Credentials) *Client {
+	baseUrl := creds.BaseUrl
+	if baseUrl == "" {
+		baseUrl = p.cfg.BaseUrl
+	}
+	opts := []Option{WithHTTPClient(p.httpClient)}
+	if p.cfg.Options != nil {
+		opts = append(opts, p.cfg.Options(appId)...)
+	}
+	return NewClient(appId, creds.AppSecret, baseUrl, opts...)
+}
+
+// Refresh looks up appId's credentials now, replacing its client if they
+// changed. Call it when a secret is known to have been rotated. On a lookup
+// error the current client, if any, is kept and the error returned.
+func (p *ClientPool) Refresh(appId string) error {
+	_, err := p.client(appId, true)
+	return err
+}
+
+// Remove drops appId's client. Its next use looks the credentials up again.
+func (p *ClientPool) Remove(appId string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.tenants, appId)
+}
+
+// AppIds returns the appIds that currently have a client.
+func (p *ClientPool) AppIds() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	ids := make([]string, 0, len(p.tenants))
+	for id := range p.tenants {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Secret returns appId's current appSecret. It has the SecretLookup
+// signature, so a Verifier can check requests for every tenant:
+//
+//	client.VerifyMiddleware(pool.Secret, next)
+func (p *ClientPool) Secret(appId string) (string, error) {
+	if _, err := p.Client(appId); err != nil {
+		return "", err
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if t := p.tenants[appId]; t != nil {
+		return t.creds.AppSecret, nil
+	}
+	return "", fmt.Errorf("%w %s", ErrUnknownApp, appId)
+}
+
+// CloseIdleConnections closes idle connections of the shared transport.
+func (p *ClientPool) CloseIdleConnections() {
+	p.httpClient.CloseIdleConnections()
+}
+
+// HandleNotifications routes notifications for appId to h. An empty appId
+// registers the handler for tenants without one of their own.
+func (p *ClientPool) HandleNotifications(appId string, h NotificationHandler) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.handlers[appId] = h
+}
+
+// Dispatch passes n to the handler for its appId, together with that
+// tenant's client. The appId must be known to the CredentialLookup, so
+// notifications for other apps are rejected with ErrUnknownApp.
+func (p *ClientPool) Dispatch(ctx context.Context, n *Notification) error {
+	appId := n.AppIdString()
+	if appId == "" {
+		return fmt.Errorf("notification %s has no appId", n.NotifyId)
+	}
+	p.lock.RLock()
+	h, ok := p.handlers[appId]
+	if !ok {
+		h, ok = p.handlers[""]
+	}
+	p.lock.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %s: no notification handler", ErrUnknownApp, appId)
+	}
+	c, err := p.Client(appId)
+	if err != nil {
+		return err
+	}
+	return h(ctx, c, n)
+}
+
+// NotificationEndpoint returns an http.Handler for the platform's webhook.
+// It decodes the Notification, runs Dispatch and answers with a
+// NotificationResponse: code 0 on success, otherwise the HTTP status, which
+// is 400 for unreadable or unknown-app notifications and 500 when the
+// handler fails.
+func (p *ClientPool) NotificationEndpoint() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		status := http.StatusOK
+		err := json.NewDecoder(r.Body).Decode(&n)
+		if err != nil {
+			status = http.StatusBadRequest
+			err = fmt.Errorf("invalid notification: %w", err)
+		} else if err = p.Dispatch(r.Context(), &n); err != nil {
+			status = http.StatusInternalServerError
+			if errors.Is(err, ErrUnknownApp) || n.AppIdString() == "" {
+				status = http.StatusBadRequest
+			}
+		}
+
+		ack := NotificationResponse{Code: 0, Msg: "ok"}
+		if err != nil {
+			ack = NotificationResponse{Code: status, Msg: err.Error()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ack)
+	})
+}
@@ -0,0 +1,238 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+	"github.com/leychan/yinsuda-music/pkg/mockserver"
+)
+
+type countingTransport struct{ n int32 }
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.n, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestClientPool(t *testing.T) {
+	f := mockserver.DefaultFixture()
+	f.Apps["2002"] = "secret-2002"
+	srv := mockserver.New(f)
+	defer srv.Close()
+
+	var secret atomic.Value
+	secret.Store("stale-secret")
+	var lookups int32
+	lookup := func(appId string) (client.Credentials, error) {
+		atomic.AddInt32(&lookups, 1)
+		switch appId {
+		case mockserver.TestAppId:
+			return client.Credentials{AppSecret: mockserver.TestAppSecret}, nil
+		case "2002":
+			return client.Credentials{AppSecret: secret.Load().(string)}, nil
+		}
+		return client.Credentials{}, client.ErrUnknownApp
+	}
+	transport := &countingTransport{}
+	pool := client.NewClientPool(client.PoolConfig{
+		BaseUrl:    srv.URL,
+		Lookup:     lookup,
+		HTTPClient: &http.Client{Transport: transport},
+	})
+
+	// Lazy, cached creation.
+	if n := len(pool.AppIds()); n != 0 {
+		t.Fatalf("Expected no clients before use, got %d", n)
+	}
+	c1, err := pool.Client(mockserver.TestAppId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := pool.Client(mockserver.TestAppId); again != c1 || atomic.LoadInt32(&lookups) != 1 {
+		t.Errorf("Expected the cached client after one lookup, got %d lookups", lookups)
+	}
+	if _, err := c1.GetSongInfo([]string{"S001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale secret fails until the tenant is refreshed.
+	c2, err := pool.Client("2002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var authErr *client.AuthError
+	if _, err := c2.GetSongInfo([]string{"S001"}); !errors.As(err, &authErr) {
+		t.Fatalf("Expected AuthError with stale secret, got %v", err)
+	}
+	secret.Store("secret-2002")
+	if err := pool.Refresh("2002"); err != nil {
+		t.Fatal(err)
+	}
+	rotated, _ := pool.Client("2002")
+	if rotated == c2 {
+		t.Fatal("Expected a new client after rotation")
+	}
+	if _, err := rotated.GetSongInfo([]string{"S001"}); err != nil {
+		t.Fatalf("rotated client: %v", err)
+	}
+	if s, _ := pool.Secret("2002"); s != "secret-2002" {
+		t.Errorf("Expected rotated secret, got %q", s)
+	}
+	// Refreshing unchanged credentials keeps the client and its token.
+	if err := pool.Refresh(mockserver.TestAppId); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := pool.Client(mockserver.TestAppId); again != c1 {
+		t.Error("unchanged credentials replaced the client")
+	}
+
+	// Every tenant's requests, token fetches included, share the transport.
+	if n := atomic.LoadInt32(&transport.n); n != 5 {
+		t.Errorf("Expected 5 round trips through the shared transport, got %d", n)
+	}
+	if _, err := pool.Client("9999"); !errors.Is(err, client.ErrUnknownApp) {
+		t.Errorf("Expected ErrUnknownApp, got %v", err)
+	}
+}
+
+func TestClientPool_Concurrent(t *testing.T) {
+	srv := mockserver.New(mockserver.DefaultFixture())
+	defer srv.Close()
+	pool := client.NewClientPool(client.PoolConfig{
+		BaseUrl: srv.URL,
+		Lookup: client.StaticCredentials(map[string]client.Credentials{
+			mockserver.TestAppId: {AppSecret: mockserver.TestAppSecret},
+		}),
+	})
+
+	clients := make([]*client.Client, 16)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := pool.Client(mockserver.TestAppId)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			clients[i] = c
+			if _, err := c.GetSongInfo([]string{"S001"}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	kept, _ := pool.Client(mockserver.TestAppId)
+	for i, c := range clients {
+		if c != kept {
+			t.Errorf("goroutine %d got a client that was not kept", i)
+		}
+	}
+}
+
+func TestClientPool_Notifications(t *testing.T) {
+	srv := mockserver.New(mockserver.DefaultFixture())
+	defer srv.Close()
+	pool := client.NewClientPool(client.PoolConfig{
+		BaseUrl: srv.URL,
+		Lookup: client.StaticCredentials(map[string]client.Credentials{
+			mockserver.TestAppId: {AppSecret: mockserver.TestAppSecret},
+			"2002":               {AppSecret: "secret-2002"},
+		}),
+	})
+
+	var lock sync.Mutex
+	got := map[string][]string{}
+	record := func(tenant string) client.NotificationHandler {
+		return func(ctx context.Context, c *client.Client, n *client.Notification) error {
+			want, _ := pool.Client(n.AppIdString())
+			if c != want {
+				t.Errorf("%s: handler got another tenant's client", tenant)
+			}
+			if n.NotifyId == "fail" {
+				return errors.New("store unavailable")
+			}
+			lock.Lock()
+			got[tenant] = append(got[tenant], n.NotifyId)
+			lock.Unlock()
+			return nil
+		}
+	}
+	pool.HandleNotifications(mockserver.TestAppId, record("main"))
+	pool.HandleNotifications("", record("fallback"))
+	hook := httptest.NewServer(pool.NotificationEndpoint())
+	defer hook.Close()
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"notifyId":"n1","notifyType":"SONG","appId":"1009232"}`, 0},
+		{`{"notifyId":"n2","notifyType":"SONG","appId":1009232}`, 0},
+		{`{"notifyId":"n3","notifyType":"SONG_LIST","appId":2002}`, 0},
+		{`{"notifyId":"n4","notifyType":"SONG","appId":"3003"}`, 400},
+		{`{"notifyId":"n5","notifyType":"SONG"}`, 400},
+		{`{"notifyId":"fail","notifyType":"SONG","appId":"1009232"}`, 500},
+		{`not json`, 400},
+	}
+	for _, tc := range cases {
+		resp, err := http.Post(hook.URL, "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ack client.NotificationResponse
+		json.NewDecoder(resp.Body).Decode(&ack)
+		resp.Body.Close()
+		if ack.Code != tc.code || (tc.code != 0 && resp.StatusCode != tc.code) {
+			t.Errorf("%s: Expected code %d, got %d/%d %q", tc.body, tc.code, resp.StatusCode, ack.Code, ack.Msg)
+		}
+	}
+	if strings.Join(got["main"], ",") != "n1,n2" || strings.Join(got["fallback"], ",") != "n3" {
+		t.Errorf("unexpected routing %v", got)
+	}
+}
+
+func TestClientPool_LookupOutage(t *testing.T) {
+	var lookups int32
+	var failing atomic.Bool
+	pool := client.NewClientPool(client.PoolConfig{
+		BaseUrl:         "http://127.0.0.1:0",
+		RefreshInterval: 200 * time.Millisecond,
+		Lookup: func(appId string) (client.Credentials, error) {
+			atomic.AddInt32(&lookups, 1)
+			if failing.Load() {
+				return client.Credentials{}, errors.New("secret store unavailable")
+			}
+			return client.Credentials{AppSecret: "s"}, nil
+		},
+	})
+	c, err := pool.Client("1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing.Store(true)
+	time.Sleep(250 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		got, err := pool.Client("1001")
+		if err != nil || got != c {
+			t.Fatalf("Expected the current client during the outage, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&lookups); n != 2 {
+		t.Errorf("Expected one lookup per interval during the outage, got %d in total", n)
+	}
+	if err := pool.Refresh("1001"); err == nil {
+		t.Error("Expected Refresh to report the lookup error")
+	}
+}
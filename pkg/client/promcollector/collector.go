@@ -0,0 +1,163 @@
+// Package promcollector exports client metrics to Prometheus. It is a
+// module of its own, so services that don't use Prometheus don't pay for
+// client_golang.
+//
+//	col := promcollector.New()
+//	prometheus.MustRegister(col)
+//	c := client.NewClient(appId, appSecret, baseUrl, col.Option())
+//	http.Handle("/metrics", promhttp.Handler())
+package promcollector
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Collector implements client.Metrics and prometheus.Collector.
+type Collector struct {
+	requests        *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	refreshes       prometheus.Counter
+	refreshFailures prometheus.Counter
+	cache           *prometheus.CounterVec
+	tokenExpiry     *prometheus.Desc
+
+	lock   sync.Mutex
+	tokens *client.TokenProvider
+	now    func() time.Time
+}
+
+// New returns a Collector with the default latency buckets.
+func New() *Collector {
+	return NewWithBuckets(prometheus.DefBuckets)
+}
+
+// NewWithBuckets returns a Collector whose latency histogram uses buckets,
+// in seconds.
+func NewWithBuckets(buckets []float64) *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yinsuda_client_requests_total",
+			Help: "Completed API calls by endpoint, HTTP status and business code.",
+		}, []string{"path", "status", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yinsuda_client_request_errors_total",
+			Help: "Failed API calls by endpoint and business code, or http_<status>, breaker_open or transport.",
+		}, []string{"path", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "yinsuda_client_request_duration_seconds",
+			Help:    "API call latency, including limiter waits and token fetches.",
+			Buckets: buckets,
+		}, []string{"path"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yinsuda_client_in_flight_requests",
+			Help: "Requests currently being sent, by endpoint.",
+		}, []string{"path"}),
+		refreshes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "yinsuda_client_token_refreshes_total",
+			Help: "Access token fetches.",
+		}),
+		refreshFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "yinsuda_client_token_refresh_failures_total",
+			Help: "Access token fetches that failed.",
+		}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "yinsuda_client_cache_lookups_total",
+			Help: "Cache lookups by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		tokenExpiry: prometheus.NewDesc("yinsuda_client_token_expiry_seconds",
+			"Seconds until the cached access token expires; negative once expired.", nil, nil),
+		now: time.Now,
+	}
+}
+
+// Option connects the collector to the client being built: it adds the
+// collector to the client's Metrics with client.WithAddedMetrics, keeping
+// any already installed, adds an interceptor tracking in-flight requests and
+// reads token expiry from the client's TokenProvider. Use one Collector per
+// Client, and pass Option after any WithTelemetry that sets Metrics.
+func (col *Collector) Option() client.Option {
+	return func(c *client.Client) {
+		client.WithAddedMetrics(col)(c)
+		client.WithInterceptors(col.trackInFlight)(c)
+		col.lock.Lock()
+		col.tokens = c.TokenProvider()
+		col.lock.Unlock()
+	}
+}
+
+func (col *Collector) trackInFlight(next client.Handler) client.Handler {
+	return func(ex *client.Exchange) error {
+		g := col.inFlight.WithLabelValues(client.MetricPath(ex.Path))
+		g.Inc()
+		defer g.Dec()
+		return next(ex)
+	}
+}
+
+// RequestDone implements client.Metrics.
+func (col *Collector) RequestDone(ctx context.Context, path string, latency time.Duration, status, code int, err error) {
+	path = client.MetricPath(path)
+	col.requests.WithLabelValues(path, strconv.Itoa(status), strconv.Itoa(code)).Inc()
+	col.duration.WithLabelValues(path).Observe(latency.Seconds())
+	if err != nil {
+		col.errors.WithLabelValues(path, client.ErrorClass(status, code, err)).Inc()
+	}
+}
+
+// TokenRefreshed implements client.Metrics.
+func (col *Collector) TokenRefreshed(ctx context.Context, appId string, latency time.Duration, err error) {
+	col.refreshes.Inc()
+	if err != nil {
+		col.refreshFailures.Inc()
+	}
+}
+
+// CacheLookup implements client.Metrics.
+func (col *Collector) CacheLookup(ctx context.Context, cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	col.cache.WithLabelValues(cache, result).Inc()
+}
+
+// Describe implements prometheus.Collector.
+func (col *Collector) Describe(ch chan<- *prometheus.Desc) {
+	col.requests.Describe(ch)
+	col.errors.Describe(ch)
+	col.duration.Describe(ch)
+	col.inFlight.Describe(ch)
+	col.refreshes.Describe(ch)
+	col.refreshFailures.Describe(ch)
+	col.cache.Describe(ch)
+	ch <- col.tokenExpiry
+}
+
+// Collect implements prometheus.Collector.
+func (col *Collector) Collect(ch chan<- prometheus.Metric) {
+	col.requests.Collect(ch)
+	col.errors.Collect(ch)
+	col.duration.Collect(ch)
+	col.inFlight.Collect(ch)
+	col.refreshes.Collect(ch)
+	col.refreshFailures.Collect(ch)
+	col.cache.Collect(ch)
+
+	col.lock.Lock()
+	tokens := col.tokens
+	col.lock.Unlock()
+	if tokens != nil {
+		if exp := tokens.ExpiresAt(); !exp.IsZero() {
+			ch <- prometheus.MustNewConstMetric(col.tokenExpiry, prometheus.GaugeValue, exp.Sub(col.now()).Seconds())
+		}
+	}
+}
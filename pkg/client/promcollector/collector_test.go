@@ -0,0 +1,89 @@
+package promcollector_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+	"github.com/leychan/yinsuda-music/pkg/client/promcollector"
+	"github.com/leychan/yinsuda-music/pkg/mockserver"
+)
+
+// countingMetrics stands in for Metrics installed before the collector.
+type countingMetrics struct{ requests int }
+
+func (m *countingMetrics) RequestDone(context.Context, string, time.Duration, int, int, error) {
+	m.requests++
+}
+func (m *countingMetrics) TokenRefreshed(context.Context, string, time.Duration, error) {}
+func (m *countingMetrics) CacheLookup(context.Context, string, bool)                    {}
+
+func TestCollector(t *testing.T) {
+	srv := mockserver.New(mockserver.DefaultFixture())
+	defer srv.Close()
+
+	existing := &countingMetrics{}
+	col := promcollector.New()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(col)
+	c := client.NewClient(mockserver.TestAppId, mockserver.TestAppSecret, srv.URL,
+		client.WithTelemetry(nil, existing), col.Option())
+
+	if _, err := c.GetSongInfo([]string{"S001"}); err != nil {
+		t.Fatalf("GetSongInfo failed: %v", err)
+	}
+	srv.InjectFault(mockserver.PathGetSongUrl, mockserver.Fault{StatusCode: 502, Times: 1})
+	c.GetSongUrl(&client.GetSongUrlRequest{SongId: "S001"})
+	srv.InjectFault(mockserver.PathGetSongUrl, mockserver.Fault{Code: mockserver.CodeNotFound, Message: "no such song", Times: 1})
+	c.GetSongUrl(&client.GetSongUrlRequest{SongId: "S001"})
+	c.Do("POST", "/elsewhere", nil, nil, &client.BaseResponse{})
+
+	if existing.requests != 4 {
+		t.Errorf("previously installed Metrics saw %d requests, want 4", existing.requests)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	want := `
+# HELP yinsuda_client_request_errors_total Failed API calls by endpoint and business code, or http_<status>, breaker_open or transport.
+# TYPE yinsuda_client_request_errors_total counter
+yinsuda_client_request_errors_total{code="404",path="/mcrc-sas/yinsuda/getSongUrl"} 1
+yinsuda_client_request_errors_total{code="http_502",path="/mcrc-sas/yinsuda/getSongUrl"} 1
+yinsuda_client_request_errors_total{code="http_404",path="other"} 1
+# HELP yinsuda_client_requests_total Completed API calls by endpoint, HTTP status and business code.
+# TYPE yinsuda_client_requests_total counter
+yinsuda_client_requests_total{code="0",path="/mcrc-sas/yinsuda/getSongInfo",status="200"} 1
+yinsuda_client_requests_total{code="0",path="/mcrc-sas/yinsuda/getSongUrl",status="502"} 1
+yinsuda_client_requests_total{code="404",path="/mcrc-sas/yinsuda/getSongUrl",status="200"} 1
+yinsuda_client_requests_total{code="0",path="other",status="404"} 1
+# HELP yinsuda_client_in_flight_requests Requests currently being sent, by endpoint.
+# TYPE yinsuda_client_in_flight_requests gauge
+yinsuda_client_in_flight_requests{path="/mcrc-sas/yinsuda/getSongInfo"} 0
+yinsuda_client_in_flight_requests{path="/mcrc-sas/yinsuda/getSongUrl"} 0
+yinsuda_client_in_flight_requests{path="other"} 0
+# HELP yinsuda_client_token_refreshes_total Access token fetches.
+# TYPE yinsuda_client_token_refreshes_total counter
+yinsuda_client_token_refreshes_total 1
+# HELP yinsuda_client_token_refresh_failures_total Access token fetches that failed.
+# TYPE yinsuda_client_token_refresh_failures_total counter
+yinsuda_client_token_refresh_failures_total 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"yinsuda_client_requests_total", "yinsuda_client_request_errors_total",
+		"yinsuda_client_in_flight_requests", "yinsuda_client_token_refreshes_total",
+		"yinsuda_client_token_refresh_failures_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(col, "yinsuda_client_request_duration_seconds"); n != 3 {
+		t.Errorf("Expected 3 latency series, got %d", n)
+	}
+	if n := testutil.CollectAndCount(col, "yinsuda_client_token_expiry_seconds"); n != 1 {
+		t.Errorf("Expected token expiry gauge, got %d series", n)
+	}
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// EscapeMode selects how query keys and values are percent-encoded.
+type EscapeMode int
+
+const (
+	// EscapeForm is Go's url.QueryEscape: space becomes '+', and
+	// A-Z a-z 0-9 - _ . ~ are kept. This is what url.Values.Encode does.
+	EscapeForm EscapeMode = iota
+	// EscapeRFC3986 keeps only the RFC 3986 unreserved set
+	// (A-Z a-z 0-9 - _ . ~) and encodes space as %20.
+	EscapeRFC3986
+	// EscapeJava matches java.net.URLEncoder: space becomes '+', and
+	// A-Z a-z 0-9 - _ . * are kept ('~' is encoded).
+	EscapeJava
+)
+
+// QueryEncoder builds the canonical query string used both in the request
+// URL and in the signature. Keys are always sorted; repeated keys keep the
+// order their values were added in unless SortValues is set.
+// The zero value is identical to url.Values.Encode.
+type QueryEncoder struct {
+	Escape     EscapeMode
+	SortValues bool
+}
+
+// Encode returns the query string without the leading '?'.
+func (e QueryEncoder) Encode(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		values := q[k]
+		if e.SortValues {
+			values = append([]string(nil), values...)
+			sort.Strings(values)
+		}
+		key := e.escape(k)
+		for _, v := range values {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(key)
+			b.WriteByte('=')
+			b.WriteString(e.escape(v))
+		}
+	}
+	return b.String()
+}
+
+func (e QueryEncoder) escape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.':
+			b.WriteByte(c)
+		case c == '~' && e.Escape != EscapeJava:
+			b.WriteByte(c)
+		case c == '*' && e.Escape == EscapeJava:
+			b.WriteByte(c)
+		case c == ' ' && e.Escape != EscapeRFC3986:
+			b.WriteByte('+')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestQueryEncoder(t *testing.T) {
+	q := url.Values{
+		"searchText": {"住在心里"},
+		"r":          {"a b&c=d/e~f*g+h"},
+		"tag":        {"z", "a"},
+	}
+
+	cases := []struct {
+		name string
+		enc  QueryEncoder
+		want string
+	}{
+		{"form", QueryEncoder{},
+			"r=a+b%26c%3Dd%2Fe~f%2Ag%2Bh&searchText=%E4%BD%8F%E5%9C%A8%E5%BF%83%E9%87%8C&tag=z&tag=a"},
+		{"rfc3986", QueryEncoder{Escape: EscapeRFC3986},
+			"r=a%20b%26c%3Dd%2Fe~f%2Ag%2Bh&searchText=%E4%BD%8F%E5%9C%A8%E5%BF%83%E9%87%8C&tag=z&tag=a"},
+		{"java", QueryEncoder{Escape: EscapeJava},
+			"r=a+b%26c%3Dd%2Fe%7Ef*g%2Bh&searchText=%E4%BD%8F%E5%9C%A8%E5%BF%83%E9%87%8C&tag=z&tag=a"},
+		{"sorted values", QueryEncoder{SortValues: true},
+			"r=a+b%26c%3Dd%2Fe~f%2Ag%2Bh&searchText=%E4%BD%8F%E5%9C%A8%E5%BF%83%E9%87%8C&tag=a&tag=z"},
+	}
+	for _, tc := range cases {
+		if got := tc.enc.Encode(q); got != tc.want {
+			t.Errorf("%s:\nExpected: %s\nActual:   %s", tc.name, tc.want, got)
+		}
+	}
+
+	// The zero value must stay byte-identical to url.Values.Encode.
+	if got := (QueryEncoder{}).Encode(q); got != q.Encode() {
+		t.Errorf("zero encoder differs from url.Values.Encode: %s", got)
+	}
+
+	// Every mode must round-trip through Go's parser.
+	for _, tc := range cases {
+		parsed, err := url.ParseQuery(tc.enc.Encode(q))
+		if err != nil || parsed.Get("searchText") != "住在心里" || parsed.Get("r") != "a b&c=d/e~f*g+h" {
+			t.Errorf("%s: round-trip failed: %v %v", tc.name, parsed, err)
+		}
+	}
+}
+
+func TestClient_WithQueryEncoder(t *testing.T) {
+	var gotRawQuery string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	lookup := func(string) (string, error) { return "testAppSecret", nil }
+	mux.Handle("/api/search", VerifyMiddleware(lookup, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRawQuery = r.URL.RawQuery
+		json.NewEncoder(w).Encode(BaseResponse{Code: 0, Success: true})
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithQueryEncoder(QueryEncoder{Escape: EscapeRFC3986}))
+	query := url.Values{"searchText": {"住在 心里"}}
+	if err := client.Do("GET", "/api/search", query, nil, &BaseResponse{}); err != nil {
+		t.Fatalf("request with RFC 3986 query rejected: %v", err)
+	}
+	if gotRawQuery != "searchText=%E4%BD%8F%E5%9C%A8%20%E5%BF%83%E9%87%8C" {
+		t.Errorf("unexpected raw query %s", gotRawQuery)
+	}
+}
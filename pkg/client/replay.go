@@ -0,0 +1,168 @@
+package client
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Replay rejection reasons, in addition to the Verify reasons.
+const (
+	ReasonReplayed   VerifyReason = "replayed"
+	ReasonNonceFull  VerifyReason = "nonce_store_full"
+	ReasonNonceStore VerifyReason = "nonce_store_error"
+)
+
+// NonceStore remembers nonces until they expire.
+type NonceStore interface {
+	// Add records nonce until expiresAt. It returns ErrNonceSeen if the nonce
+	// is already present and unexpired, or ErrNonceStoreFull if it has no room.
+	Add(nonce string, expiresAt time.Time) error
+}
+
+var (
+	ErrNonceSeen      = errors.New("nonce already seen")
+	ErrNonceStoreFull = errors.New("nonce store full")
+)
+
+// DefaultMaxNonces is the capacity of a MemoryNonceStore created with max <= 0.
+const DefaultMaxNonces = 1 << 20
+
+// MemoryNonceStore is a bounded in-process NonceStore. Expired entries are
+// purged as new ones arrive. When full it refuses new nonces rather than
+// forgetting unexpired ones, since forgetting would reopen a replay window;
+// size it for peak requests per replay window.
+type MemoryNonceStore struct {
+	max int
+
+	lock    sync.Mutex
+	expires map[string]time.Time
+	queue   nonceHeap
+	now     func() time.Time
+}
+
+func NewMemoryNonceStore(max int) *MemoryNonceStore {
+	if max <= 0 {
+		max = DefaultMaxNonces
+	}
+	return &MemoryNonceStore{
+		max:     max,
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryNonceStore) Add(nonce string, expiresAt time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	for len(s.queue) > 0 && !s.queue[0].expiresAt.After(now) {
+		e := heap.Pop(&s.queue).(nonceEntry)
+		if s.expires[e.nonce] == e.expiresAt {
+			delete(s.expires, e.nonce)
+		}
+	}
+
+	if exp, ok := s.expires[nonce]; ok && exp.After(now) {
+		return ErrNonceSeen
+	}
+	if len(s.expires) >= s.max {
+		return ErrNonceStoreFull
+	}
+	s.expires[nonce] = expiresAt
+	heap.Push(&s.queue, nonceEntry{nonce: nonce, expiresAt: expiresAt})
+	return nil
+}
+
+// Len returns the number of remembered nonces, including expired ones not yet purged.
+func (s *MemoryNonceStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.expires)
+}
+
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// nonceHeap orders entries by expiry, earliest first.
+type nonceHeap []nonceEntry
+
+func (h nonceHeap) Len() int            { return len(h) }
+func (h nonceHeap) Less(i, j int) bool  { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h nonceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x interface{}) { *h = append(*h, x.(nonceEntry)) }
+func (h *nonceHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// DefaultReplayWindow is the freshness window used when ReplayGuard.Window is 0.
+const DefaultReplayWindow = 5 * time.Minute
+
+// ReplayGuard rejects requests whose timestamp header is outside Window, or
+// whose traceId was already accepted for the same appId within it.
+// Attach it to a Verifier so only correctly signed requests consume a traceId.
+type ReplayGuard struct {
+	Store  NonceStore
+	Window time.Duration
+	// Location is the zone of the timestamp header; defaults to time.Local.
+	Location *time.Location
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewReplayGuard returns a guard with an in-memory store holding up to maxNonces traceIds.
+func NewReplayGuard(window time.Duration, maxNonces int) *ReplayGuard {
+	return &ReplayGuard{Store: NewMemoryNonceStore(maxNonces), Window: window}
+}
+
+// Check applies the guard to r. Failures are returned as *VerifyError.
+func (g *ReplayGuard) Check(r *http.Request) error {
+	appId := r.Header.Get("appId")
+	traceId := r.Header.Get("traceId")
+	ts := r.Header.Get("timestamp")
+	if traceId == "" {
+		return &VerifyError{Reason: ReasonMissingHeader, Detail: "traceId"}
+	}
+
+	loc := g.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation("20060102150405", ts, loc)
+	if err != nil {
+		return &VerifyError{Reason: ReasonBadTimestamp, Detail: ts, Err: err}
+	}
+
+	window := g.Window
+	if window <= 0 {
+		window = DefaultReplayWindow
+	}
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	if skew := now().Sub(t); skew > window || skew < -window {
+		return &VerifyError{Reason: ReasonTimestampSkew, Detail: fmt.Sprintf("timestamp %s is %s from now", ts, skew.Round(time.Second))}
+	}
+
+	// The nonce must outlive the last moment its timestamp is still acceptable.
+	switch err := g.Store.Add(appId+":"+traceId, t.Add(window)); err {
+	case nil:
+		return nil
+	case ErrNonceSeen:
+		return &VerifyError{Reason: ReasonReplayed, Detail: "traceId " + traceId}
+	case ErrNonceStoreFull:
+		return &VerifyError{Reason: ReasonNonceFull, Detail: "replay protection at capacity", Err: err}
+	default:
+		return &VerifyError{Reason: ReasonNonceStore, Detail: "nonce store failed", Err: err}
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayGuard(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	store := NewMemoryNonceStore(2)
+	store.now = clock
+	v := &Verifier{
+		Lookup:   func(string) (string, error) { return "s", nil },
+		Location: time.UTC,
+		Now:      clock,
+		Replay:   &ReplayGuard{Store: store, Window: time.Minute, Location: time.UTC, Now: clock},
+	}
+
+	request := func(traceId, ts, sign string) error {
+		params := SignParams{AppId: "a", AccessToken: "t", Timestamp: ts, SignMethod: "md5", TraceId: traceId}
+		r := httptest.NewRequest("POST", "/p", strings.NewReader("{}"))
+		for k, val := range map[string]string{"appId": "a", "accessToken": "t", "timestamp": ts, "signMethod": "md5", "traceId": traceId} {
+			r.Header.Set(k, val)
+		}
+		if sign == "" {
+			sign = CalculateSign(params, []byte("{}"), "/p", url.Values{}, "s")
+		}
+		r.Header.Set("sign", sign)
+		return v.Verify(r)
+	}
+	reason := func(err error) VerifyReason {
+		var vErr *VerifyError
+		if errors.As(err, &vErr) {
+			return vErr.Reason
+		}
+		return ""
+	}
+
+	if err := request("t1", "20240501120000", ""); err != nil {
+		t.Fatalf("fresh request rejected: %v", err)
+	}
+	if r := reason(request("t1", "20240501120000", "")); r != ReasonReplayed {
+		t.Errorf("expected replay rejection, got %q", r)
+	}
+	// A badly signed request must not burn its traceId.
+	if r := reason(request("t2", "20240501120000", "bogus")); r != ReasonSignMismatch {
+		t.Errorf("expected sign mismatch, got %q", r)
+	}
+	if err := request("t2", "20240501120000", ""); err != nil {
+		t.Errorf("t2 should still be usable: %v", err)
+	}
+	// Outside the guard window, even though within the verifier's default skew.
+	if r := reason(request("t3", "20240501115800", "")); r != ReasonTimestampSkew {
+		t.Errorf("expected skew rejection, got %q", r)
+	}
+	// Store holds t1 and t2: full until they expire.
+	if r := reason(request("t4", "20240501120000", "")); r != ReasonNonceFull {
+		t.Errorf("expected full store, got %q", r)
+	}
+
+	now = now.Add(61 * time.Second)
+	if err := request("t4", "20240501120030", ""); err != nil {
+		t.Errorf("expired nonces should have been purged: %v", err)
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected 1 remembered nonce, got %d", store.Len())
+	}
+}
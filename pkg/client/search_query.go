@@ -0,0 +1,202 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// SearchType selects which fields SearchSong matches against. It is an
+// alias of int, so SearchSongRequest.SearchType stays a plain int field and
+// both the constants below and int values can be assigned to it.
+type SearchType = int
+
+const (
+	SearchTypeFull       SearchType = 1 // song name, artist name, etc.
+	SearchTypeSongName   SearchType = 2
+	SearchTypeArtistName SearchType = 3
+)
+
+// ValidSearchType reports whether t is one of the SearchType constants.
+func ValidSearchType(t SearchType) bool {
+	return t >= SearchTypeFull && t <= SearchTypeArtistName
+}
+
+// StatusFilter is the tri-state song status filter for SearchQuery. Its
+// values are names, not the 0/1 status the API and Song.Status use, so a
+// status cannot be converted to a filter by accident. The zero value does
+// not filter.
+type StatusFilter string
+
+const (
+	StatusAny         StatusFilter = ""            // status omitted from the request
+	StatusAvailable   StatusFilter = "available"   // status=1
+	StatusUnavailable StatusFilter = "unavailable" // status=0
+)
+
+// Match reports whether a song with the given Song.Status passes f.
+// Unknown filters match nothing.
+func (f StatusFilter) Match(status int) bool {
+	switch f {
+	case StatusAny:
+		return true
+	case StatusAvailable:
+		return status == 1
+	case StatusUnavailable:
+		return status == 0
+	}
+	return false
+}
+
+// defaultSearchLimit is the page size of a new SearchQuery.
+const defaultSearchLimit = 20
+
+// SearchQuery builds a validated SearchSongRequest.
+//
+//	req, err := client.NewSearchQuery("住在心里").
+//		Type(client.SearchTypeSongName).
+//		Status(client.StatusUnavailable).
+//		Page(0, 20).
+//		Build()
+type SearchQuery struct {
+	text       string
+	searchType SearchType
+	status     StatusFilter
+	offset     int
+	limit      int
+}
+
+// NewSearchQuery starts a full-type search for text, unfiltered, first page of 20.
+func NewSearchQuery(text string) *SearchQuery {
+	return &SearchQuery{
+		text:       text,
+		searchType: SearchTypeFull,
+		limit:      defaultSearchLimit,
+	}
+}
+
+func (q *SearchQuery) Type(t SearchType) *SearchQuery {
+	q.searchType = t
+	return q
+}
+
+func (q *SearchQuery) Status(s StatusFilter) *SearchQuery {
+	q.status = s
+	return q
+}
+
+func (q *SearchQuery) Page(offset, limit int) *SearchQuery {
+	q.offset = offset
+	q.limit = limit
+	return q
+}
+
+// Build validates the query and returns the wire request.
+func (q *SearchQuery) Build() (*SearchSongRequest, error) {
+	if q.text == "" {
+		return nil, fmt.Errorf("search text is required")
+	}
+	if !ValidSearchType(q.searchType) {
+		return nil, fmt.Errorf("invalid search type %d", q.searchType)
+	}
+	if q.offset < 0 {
+		return nil, fmt.Errorf("offset must be >= 0, got %d", q.offset)
+	}
+	if q.limit < 1 {
+		return nil, fmt.Errorf("limit must be at least 1, got %d", q.limit)
+	}
+
+	req := &SearchSongRequest{
+		SearchText: q.text,
+		SearchType: q.searchType,
+		Offset:     q.offset,
+		Limit:      q.limit,
+	}
+	switch q.status {
+	case StatusAny:
+	case StatusAvailable:
+		req.SetStatus(1)
+	case StatusUnavailable:
+		req.SetStatus(0)
+	default:
+		return nil, fmt.Errorf("invalid status filter %q", q.status)
+	}
+	return req, nil
+}
+
+// SetStatus filters by status explicitly. Unlike assigning Status directly,
+// this also sends status 0 (unavailable), which omitempty would otherwise drop.
+func (r *SearchSongRequest) SetStatus(status int) {
+	r.Status = status
+	r.statusSet = true
+}
+
+// Filter returns the status filter the request sends: StatusAny if it sends
+// no status. ok is false if Status is neither 0 nor 1, which Validate
+// rejects; the returned filter then matches no song.
+func (r *SearchSongRequest) Filter() (f StatusFilter, ok bool) {
+	status, sent := r.wireStatus()
+	switch {
+	case !sent:
+		return StatusAny, true
+	case status == 1:
+		return StatusAvailable, true
+	case status == 0:
+		return StatusUnavailable, true
+	}
+	return StatusFilter(strconv.Itoa(status)), false
+}
+
+// wireStatus returns the status sent on the wire, and false if none is sent.
+func (r *SearchSongRequest) wireStatus() (int, bool) {
+	if r.statusSet || r.Status != 0 {
+		return r.Status, true
+	}
+	return 0, false
+}
+
+// MarshalJSON keeps the omitempty behaviour for plain struct literals but
+// emits an explicit status set through SetStatus, including 0.
+func (r SearchSongRequest) MarshalJSON() ([]byte, error) {
+	wire := struct {
+		SearchText string     `json:"searchText"`
+		SearchType SearchType `json:"searchType"`
+		Status     *int       `json:"status,omitempty"`
+		Offset     int        `json:"offset"`
+		Limit      int        `json:"limit"`
+	}{
+		SearchText: r.SearchText,
+		SearchType: r.SearchType,
+		Offset:     r.Offset,
+		Limit:      r.Limit,
+	}
+	if status, ok := r.wireStatus(); ok {
+		wire.Status = &status
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON records whether "status" was present, so a decoded request
+// round-trips an explicit status 0.
+func (r *SearchSongRequest) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		SearchText string     `json:"searchText"`
+		SearchType SearchType `json:"searchType"`
+		Status     *int       `json:"status"`
+		Offset     int        `json:"offset"`
+		Limit      int        `json:"limit"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*r = SearchSongRequest{
+		SearchText: wire.SearchText,
+		SearchType: wire.SearchType,
+		Offset:     wire.Offset,
+		Limit:      wire.Limit,
+	}
+	if wire.Status != nil {
+		r.SetStatus(*wire.Status)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSearchQuery_Build(t *testing.T) {
+	cases := []struct {
+		name   string
+		status StatusFilter
+		want   string
+	}{
+		{"any", StatusAny, `{"searchText":"住在心里","searchType":2,"offset":0,"limit":20}`},
+		{"available", StatusAvailable, `{"searchText":"住在心里","searchType":2,"status":1,"offset":0,"limit":20}`},
+		{"unavailable", StatusUnavailable, `{"searchText":"住在心里","searchType":2,"status":0,"offset":0,"limit":20}`},
+	}
+	for _, tc := range cases {
+		req, err := NewSearchQuery("住在心里").Type(SearchTypeSongName).Status(tc.status).Build()
+		if err != nil {
+			t.Fatalf("%s: Build failed: %v", tc.name, err)
+		}
+		// Marshal through a pointer as Client.Do does.
+		data, _ := json.Marshal(req)
+		if string(data) != tc.want {
+			t.Errorf("%s: wire mismatch.\nExpected: %s\nActual:   %s", tc.name, tc.want, data)
+		}
+		if f, ok := req.Filter(); !ok || f != tc.status {
+			t.Errorf("%s: Filter() = %q/%v", tc.name, f, ok)
+		}
+	}
+
+	// A plain literal keeps the historical omitempty behaviour.
+	data, _ := json.Marshal(&SearchSongRequest{SearchText: "a", SearchType: SearchTypeFull, Limit: 5})
+	if string(data) != `{"searchText":"a","searchType":1,"offset":0,"limit":5}` {
+		t.Errorf("literal wire mismatch: %s", data)
+	}
+	// SearchType stays an int field, as before SearchQuery existed.
+	searchType := 2
+	data, _ = json.Marshal(&SearchSongRequest{SearchText: "a", SearchType: searchType, Limit: 5})
+	if string(data) != `{"searchText":"a","searchType":2,"offset":0,"limit":5}` {
+		t.Errorf("int literal wire mismatch: %s", data)
+	}
+
+	invalid := []*SearchQuery{
+		NewSearchQuery(""),
+		NewSearchQuery("a").Type(0),
+		NewSearchQuery("a").Type(4),
+		NewSearchQuery("a").Page(-1, 10),
+		NewSearchQuery("a").Page(0, 0),
+		NewSearchQuery("a").Status("7"),
+	}
+	for i, q := range invalid {
+		if _, err := q.Build(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+	// The API documents no maximum page size.
+	if _, err := NewSearchQuery("a").Page(0, 500).Build(); err != nil {
+		t.Errorf("Expected a large limit to build, got %v", err)
+	}
+}
+
+func TestSearchSongRequest_UnmarshalStatusZero(t *testing.T) {
+	var req SearchSongRequest
+	if err := json.Unmarshal([]byte(`{"searchText":"a","searchType":1,"status":0,"limit":5}`), &req); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if f, ok := req.Filter(); !ok || f != StatusUnavailable {
+		t.Errorf("expected explicit status 0, got %q/%v", f, ok)
+	}
+}
+
+func TestStatusFilter_Match(t *testing.T) {
+	if !StatusAny.Match(0) || !StatusAny.Match(1) {
+		t.Error("StatusAny must match every status")
+	}
+	if !StatusAvailable.Match(1) || StatusAvailable.Match(0) {
+		t.Error("StatusAvailable must match status 1 only")
+	}
+	if !StatusUnavailable.Match(0) || StatusUnavailable.Match(1) {
+		t.Error("StatusUnavailable must match status 0 only")
+	}
+
+	req := &SearchSongRequest{SearchText: "a", Status: 7}
+	f, ok := req.Filter()
+	if ok || f.Match(0) || f.Match(1) {
+		t.Errorf("status 7: Filter() = %q/%v, want an invalid filter matching nothing", f, ok)
+	}
+}
@@ -32,7 +32,42 @@ type SignParams struct {
 // (d) md5(appSecret)
 // (e) md5(headerMd5 + bodyMd5 + urlMd5 + appSecretMd5) -> hex
 func CalculateSign(params SignParams, body []byte, urlPath string, query url.Values, appSecret string) string {
+	return CalculateSignRaw(params, body, urlPath, query.Encode(), appSecret)
+}
+
+// CalculateSignRaw is CalculateSign with the query already encoded, exactly
+// as it appears in the request URL (without '?'). Use it when the query is
+// not built by url.Values.Encode, e.g. with a non-default QueryEncoder.
+func CalculateSignRaw(params SignParams, body []byte, urlPath, rawQuery string, appSecret string) string {
 	// (a) Public params
+	headerMd5 := md5Hash([]byte(canonicalHeader(params)))
+
+	// (b) Body
+	// If body is empty or nil, we still hash it (empty string hash)
+	bodyMd5 := md5Hash(body)
+
+	// (c) URL
+	urlMd5 := md5Hash([]byte(signedPath(urlPath, rawQuery)))
+
+	// (d) AppSecret
+	appSecretMd5 := md5Hash([]byte(appSecret))
+
+	// (e) Final Sign
+	// buffer: headerMd5 (bytes) + bodyMd5 (bytes) ...
+	// The prompt says "拼接md5字节数组" (concatenate md5 byte arrays).
+	// md5Hash returns []byte.
+	finalInput := make([]byte, 0, 16*4)
+	finalInput = append(finalInput, headerMd5...)
+	finalInput = append(finalInput, bodyMd5...)
+	finalInput = append(finalInput, urlMd5...)
+	finalInput = append(finalInput, appSecretMd5...)
+
+	finalMd5 := md5.Sum(finalInput)
+	return hex.EncodeToString(finalMd5[:])
+}
+
+// canonicalHeader builds the "key:value;" string of the public params.
+func canonicalHeader(params SignParams) string {
 	// Java implementation: appId, accessToken, timestamp, signMethod, traceId, source
 	m := map[string]string{
 		"appId":       params.AppId,
@@ -56,38 +91,16 @@ func CalculateSign(params SignParams, body []byte, urlPath string, query url.Val
 	for _, k := range keys {
 		headerBuilder.WriteString(fmt.Sprintf("%s:%s;", k, m[k]))
 	}
-	headerStr := headerBuilder.String()
-	headerMd5 := md5Hash([]byte(headerStr))
-
-	// (b) Body
-	// If body is empty or nil, we still hash it (empty string hash)
-	bodyMd5 := md5Hash(body)
+	return headerBuilder.String()
+}
 
-	// (c) URL
-	// "包含query参数(如有)，不包含域名，比如/foo/bar?key=value"
-	// We need to ensure the query is formatted correctly if present.
-	// Standard url.Values.Encode() sorts keys.
-	fullPath := urlPath
-	if len(query) > 0 {
-		fullPath = fmt.Sprintf("%s?%s", urlPath, query.Encode())
+// signedPath is the URL part covered by the signature.
+// "包含query参数(如有)，不包含域名，比如/foo/bar?key=value"
+func signedPath(urlPath, rawQuery string) string {
+	if rawQuery != "" {
+		return fmt.Sprintf("%s?%s", urlPath, rawQuery)
 	}
-	urlMd5 := md5Hash([]byte(fullPath))
-
-	// (d) AppSecret
-	appSecretMd5 := md5Hash([]byte(appSecret))
-
-	// (e) Final Sign
-	// buffer: headerMd5 (bytes) + bodyMd5 (bytes) ...
-	// The prompt says "拼接md5字节数组" (concatenate md5 byte arrays).
-	// md5Hash returns []byte.
-	finalInput := make([]byte, 0, 16*4)
-	finalInput = append(finalInput, headerMd5...)
-	finalInput = append(finalInput, bodyMd5...)
-	finalInput = append(finalInput, urlMd5...)
-	finalInput = append(finalInput, appSecretMd5...)
-
-	finalMd5 := md5.Sum(finalInput)
-	return hex.EncodeToString(finalMd5[:])
+	return urlPath
 }
 
 // md5Hash returns the raw 16 bytes MD5 hash
@@ -95,3 +108,53 @@ func md5Hash(data []byte) []byte {
 	h := md5.Sum(data)
 	return h[:]
 }
+
+// SignTrace holds every intermediate value of CalculateSign, for diagnosing
+// signature mismatches against the server. Digests are lowercase hex.
+type SignTrace struct {
+	HeaderString string // canonical "key:value;" string of the public params
+	HeaderMd5    string
+	BodyMd5      string
+	SignedPath   string // exact urlPath?query that was hashed
+	UrlMd5       string
+	SecretMd5    string
+	Sign         string
+}
+
+// TraceSign computes the same signature as CalculateSign and returns all intermediate values.
+func TraceSign(params SignParams, body []byte, urlPath string, query url.Values, appSecret string) SignTrace {
+	return TraceSignRaw(params, body, urlPath, query.Encode(), appSecret)
+}
+
+// TraceSignRaw is TraceSign for an already encoded query; see CalculateSignRaw.
+func TraceSignRaw(params SignParams, body []byte, urlPath, rawQuery string, appSecret string) SignTrace {
+	tr := SignTrace{
+		HeaderString: canonicalHeader(params),
+		SignedPath:   signedPath(urlPath, rawQuery),
+		BodyMd5:      hex.EncodeToString(md5Hash(body)),
+		SecretMd5:    hex.EncodeToString(md5Hash([]byte(appSecret))),
+	}
+	tr.HeaderMd5 = hex.EncodeToString(md5Hash([]byte(tr.HeaderString)))
+	tr.UrlMd5 = hex.EncodeToString(md5Hash([]byte(tr.SignedPath)))
+	tr.Sign = CalculateSignRaw(params, body, urlPath, rawQuery, appSecret)
+	return tr
+}
+
+// Redacted returns a copy safe to log: the secret digest (which is all the
+// signature needs of the secret) and the access token are masked.
+func (t SignTrace) Redacted() SignTrace {
+	t.SecretMd5 = "[redacted]"
+	parts := strings.Split(t.HeaderString, ";")
+	for i, p := range parts {
+		if strings.HasPrefix(p, "accessToken:") {
+			parts[i] = "accessToken:[redacted]"
+		}
+	}
+	t.HeaderString = strings.Join(parts, ";")
+	return t
+}
+
+func (t SignTrace) String() string {
+	return fmt.Sprintf("header=%q headerMd5=%s bodyMd5=%s signedPath=%q urlMd5=%s secretMd5=%s sign=%s",
+		t.HeaderString, t.HeaderMd5, t.BodyMd5, t.SignedPath, t.UrlMd5, t.SecretMd5, t.Sign)
+}
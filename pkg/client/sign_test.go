@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -114,3 +115,33 @@ func TestCalculateSign_WithQuery(t *testing.T) {
 		t.Errorf("CalculateSign (Query) mismatch.\nExpected: %s\nActual:   %s", expectedSign, actualSign)
 	}
 }
+
+func TestTraceSign(t *testing.T) {
+	params := SignParams{
+		AppId:       "123456",
+		AccessToken: "token123",
+		Timestamp:   "20210101120000",
+		SignMethod:  "md5",
+		TraceId:     "trace123",
+	}
+	query := url.Values{"b": {"2"}, "a": {"1"}}
+	trace := TraceSign(params, []byte(`{"key":"value"}`), "/test/api", query, "secret123")
+
+	if trace.Sign != CalculateSign(params, []byte(`{"key":"value"}`), "/test/api", query, "secret123") {
+		t.Errorf("trace sign differs from CalculateSign")
+	}
+	if trace.SignedPath != "/test/api?a=1&b=2" {
+		t.Errorf("unexpected signed path %q", trace.SignedPath)
+	}
+	if trace.HeaderString != "accessToken:token123;appId:123456;signMethod:md5;timestamp:20210101120000;traceId:trace123;" {
+		t.Errorf("unexpected header string %q", trace.HeaderString)
+	}
+	if trace.SecretMd5 != fmt.Sprintf("%x", md5Hash([]byte("secret123"))) {
+		t.Errorf("unexpected secret md5 %s", trace.SecretMd5)
+	}
+
+	redacted := trace.Redacted().String()
+	if strings.Contains(redacted, "token123") || strings.Contains(redacted, trace.SecretMd5) {
+		t.Errorf("redacted trace leaks credentials: %s", redacted)
+	}
+}
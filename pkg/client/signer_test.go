@@ -0,0 +1,134 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Golden vectors, cross-checked against an independent implementation.
+// md5 v2: md5(md5(header) || md5(body) || md5(path?query) || md5(secret)), raw 16-byte digests concatenated.
+func TestSigner_GoldenVectors(t *testing.T) {
+	params := SignParams{
+		AppId:       "123456",
+		AccessToken: "token123",
+		Timestamp:   "20210101120000",
+		SignMethod:  "md5",
+		TraceId:     "trace123",
+	}
+	body := []byte(`{"key":"value"}`)
+	query := url.Values{"b": {"2"}, "a": {"1"}}
+
+	cases := []struct {
+		name   string
+		signer Signer
+		method string
+		body   []byte
+		query  url.Values
+		want   string
+	}{
+		{"md5 with body and query", MD5Signer{}, "md5", body, query, "d93693efe3d160faae45088120d24aa1"},
+		{"md5 empty body, no query", MD5Signer{}, "md5", nil, nil, "1095fb29e0c5eb88ae20cf5e316b7fff"},
+		{"hmac-sha256", HMACSHA256Signer{}, "hmac-sha256", body, query, "de99b3bd24137d2ee226641c4ef141860b628f8151f588216ac6f0bae566da05"},
+	}
+	for _, tc := range cases {
+		p := params
+		p.SignMethod = tc.method
+		if got := tc.signer.Sign(p, tc.body, "/test/api", tc.query.Encode(), "secret123"); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	if s, ok := LookupSigner("md5", ""); !ok || s.Version() != "v2" {
+		t.Errorf("md5 without version should resolve to v2")
+	}
+	if _, ok := LookupSigner("md5", "v3"); ok {
+		t.Errorf("unexpected md5/v3 signer")
+	}
+}
+
+func TestClient_WithSigner(t *testing.T) {
+	lookup := func(string) (string, error) { return "testAppSecret", nil }
+
+	var gotMethod string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	mux.Handle("/api/data", VerifyMiddleware(lookup, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = fmt.Sprintf("%s/%s", r.Header.Get("signMethod"), r.Header.Get("signVersion"))
+		json.NewEncoder(w).Encode(BaseResponse{Code: 0, Success: true})
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithSigner(HMACSHA256Signer{}))
+	var result BaseResponse
+	if err := client.Do("POST", "/api/data", nil, map[string]string{"k": "v"}, &result); err != nil {
+		t.Fatalf("hmac-signed request rejected: %v", err)
+	}
+	if gotMethod != "hmac-sha256/v2" {
+		t.Errorf("expected hmac-sha256/v2 headers, got %s", gotMethod)
+	}
+}
+
+func TestClient_WithSignTraceLog(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(BaseResponse{Code: 4001, Message: "签名错误", Msg: "sign error"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var logged []string
+	logf := func(format string, args ...interface{}) { logged = append(logged, fmt.Sprintf(format, args...)) }
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithSignTraceLog(logf))
+
+	err := client.Do("POST", "/api/data", url.Values{"q": {"a b"}}, map[string]string{"k": "v"}, &BaseResponse{})
+	if !IsSignError(err) {
+		t.Fatalf("expected sign error, got %v", err)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], `signedPath="/api/data?q=a+b"`) {
+		t.Fatalf("expected one trace log, got %v", logged)
+	}
+	if strings.Contains(logged[0], "accessToken:tok;") {
+		t.Errorf("trace log leaks access token: %s", logged[0])
+	}
+}
+
+func TestIsSignError(t *testing.T) {
+	cases := []struct {
+		err  *APIError
+		want bool
+	}{
+		{&APIError{StatusCode: 200, Code: 4001, Message: "签名错误"}, true},
+		{&APIError{StatusCode: 200, Code: 4001, Msg: "sign error"}, true},
+		{&APIError{StatusCode: 200, Code: 403, Message: "sign mismatch"}, true},
+		{&APIError{StatusCode: 200, Code: 403, Message: "unsupported signMethod hmac"}, true},
+		{&APIError{StatusCode: 200, Code: 1, Message: "Invalid signature"}, true},
+		{&APIError{StatusCode: 200, Code: 1, Msg: "sign is invalid"}, true},
+		{&APIError{StatusCode: 401, Body: `{"message":"sign_mismatch"}`}, true},
+		{&APIError{StatusCode: 200, Code: 1, Message: "design not found"}, false},
+		{&APIError{StatusCode: 200, Code: 1, Message: "failed to assign song"}, false},
+		{&APIError{StatusCode: 200, Code: 1, Message: "signal lost"}, false},
+		{&APIError{StatusCode: 200, Code: 1, Msg: "signup required"}, false},
+		{&APIError{StatusCode: 200, Code: 1, Message: "bad request", Body: "sign error"}, false}, // body only counts for 401/403
+		{&APIError{StatusCode: 403, Body: "<html>Please sign in</html>"}, false},
+	}
+	for _, tc := range cases {
+		if got := IsSignError(tc.err); got != tc.want {
+			t.Errorf("IsSignError(%q %q %q) = %v, want %v", tc.err.Message, tc.err.Msg, tc.err.Body, got, tc.want)
+		}
+	}
+	if IsSignError(errors.New("sign error")) {
+		t.Error("non-API errors are never sign errors")
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// streamDecoder is a Do result that decodes the response body itself as it
+// is read, instead of Do buffering it.
+type streamDecoder interface {
+	decodeStream(r io.Reader) (envelopeHeader, error)
+}
+
+// callbackError carries an error returned by a caller's callback, so it is
+// not mistaken for a decode or transport failure.
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string { return e.err.Error() }
+func (e *callbackError) Unwrap() error { return e.err }
+
+// songStream decodes an envelope whose data holds a songList, passing each
+// song to fn as soon as it is decoded. Other data fields are kept in meta.
+type songStream struct {
+	fn   func(Song) error
+	meta struct {
+		NextQueryInfo string
+		Total         int
+	}
+}
+
+func (s *songStream) decodeStream(r io.Reader) (envelopeHeader, error) {
+	var h envelopeHeader
+	var seenCode, seenSuccess bool
+	dec := json.NewDecoder(r)
+
+	err := decodeObject(dec, func(key string) error {
+		switch key {
+		case "code":
+			seenCode = true
+			return dec.Decode(&h.Code)
+		case "success":
+			seenSuccess = true
+			return dec.Decode(&h.Success)
+		case "message":
+			return dec.Decode(&h.Message)
+		case "msg":
+			return dec.Decode(&h.Msg)
+		case "traceId":
+			return dec.Decode(&h.TraceId)
+		case "data":
+			// An envelope already known to be an error is not streamed.
+			if (seenSuccess && !h.Success) || (seenCode && h.Code != 0) {
+				return skipValue(dec)
+			}
+			return s.decodeData(dec)
+		}
+		return skipValue(dec)
+	})
+	if err != nil {
+		var cbErr *callbackError
+		if errors.As(err, &cbErr) {
+			return h, err
+		}
+		return h, fmt.Errorf("failed to parse base response: %w", err)
+	}
+	return h, nil
+}
+
+func (s *songStream) decodeData(dec *json.Decoder) error {
+	return decodeObject(dec, func(key string) error {
+		switch key {
+		case "songList":
+			return decodeArray(dec, func() error {
+				var song Song
+				if err := dec.Decode(&song); err != nil {
+					return err
+				}
+				if err := s.fn(song); err != nil {
+					return &callbackError{err}
+				}
+				return nil
+			})
+		case "nextQueryInfo":
+			return dec.Decode(&s.meta.NextQueryInfo)
+		case "total":
+			return dec.Decode(&s.meta.Total)
+		}
+		return skipValue(dec)
+	})
+}
+
+// decodeObject reads a JSON object, calling field for each key with the
+// decoder positioned at its value. A null object is accepted.
+func decodeObject(dec *json.Decoder, field func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("expected object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(tok.(string)); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token() // '}'
+	return err
+}
+
+// decodeArray reads a JSON array, calling elem with the decoder positioned
+// at each element. A null array is accepted.
+func decodeArray(dec *json.Decoder, elem func() error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+	for dec.More() {
+		if err := elem(); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token() // ']'
+	return err
+}
+
+func skipValue(dec *json.Decoder) error {
+	var skip json.RawMessage
+	return dec.Decode(&skip)
+}
+
+// streamSongs runs a request whose response data holds a songList through
+// DoContext, streaming the songs to fn.
+func (c *Client) streamSongs(ctx context.Context, path string, req interface{}, fn func(Song) error) (*songStream, error) {
+	if err := c.validateRequest(req); err != nil {
+		return nil, err
+	}
+	s := &songStream{fn: fn}
+	if err := c.DoContext(ctx, "POST", path, nil, req, s); err != nil {
+		var cbErr *callbackError
+		if errors.As(err, &cbErr) {
+			return nil, cbErr.err
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// StreamSongList is GetSongList with the songs passed to fn one at a time as
+// they are decoded, so memory stays flat for large Limit values. It returns
+// the nextQueryInfo cursor. If fn returns an error the stream stops and that
+// error is returned unchanged.
+//
+// The envelope code and success are still checked. When they come before
+// data, an error envelope yields no songs; when they come after it, songs
+// may reach fn before the error is returned.
+func (c *Client) StreamSongList(ctx context.Context, req *GetSongListRequest, fn func(Song) error) (nextQueryInfo string, err error) {
+	s, err := c.streamSongs(ctx, "/mcrc-sas/yinsuda/getSongList", c.defaults.songList(req), fn)
+	if err != nil {
+		return "", err
+	}
+	return s.meta.NextQueryInfo, nil
+}
+
+// StreamSearchSong is SearchSong with the songs passed to fn as they are
+// decoded. It returns the total hit count. See StreamSongList.
+func (c *Client) StreamSearchSong(ctx context.Context, req *SearchSongRequest, fn func(Song) error) (total int, err error) {
+	s, err := c.streamSongs(ctx, "/mcrc-sas/yinsuda/searchSong", c.defaults.search(req), fn)
+	if err != nil {
+		return 0, err
+	}
+	return s.meta.Total, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_StreamSongList(t *testing.T) {
+	bodies := map[string]string{
+		"page":      `{"code":0,"message":"ok","success":true,"data":{"songList":[{"songId":"1","songName":"晴天"},{"songId":"2"},{"songId":"3"}],"nextQueryInfo":"c2","extra":{"x":[1,2]}},"traceId":"t"}`,
+		"rejected":  `{"code":1001,"success":false,"message":"bad cursor","data":{"songList":[{"songId":"1"}]}}`,
+		"late":      `{"data":{"songList":[{"songId":"1"}]},"code":1001,"success":false}`,
+		"null":      `{"code":0,"success":true,"data":null}`,
+		"malformed": `{"code":0,"success":true,"data":{"songList":[{"songId":"1"},`,
+	}
+	var gotLimit int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/getSongList", func(w http.ResponseWriter, r *http.Request) {
+		var req GetSongListRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		gotLimit = req.Limit
+		key := req.QueryInfo
+		w.Write([]byte(bodies[key]))
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/searchSong", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"success":true,"data":{"total":42,"songList":[{"songId":"9"}]}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	b := NewBreaker(BreakerConfig{ConsecutiveFailures: 1})
+	c := NewClient("testAppId", "testAppSecret", server.URL, WithBreaker(b))
+	ctx := context.Background()
+
+	stream := func(cursor string, fn func(Song) error) ([]string, string, error) {
+		var ids []string
+		next, err := c.StreamSongList(ctx, &GetSongListRequest{QueryInfo: cursor}, func(s Song) error {
+			ids = append(ids, s.SongId)
+			if fn != nil {
+				return fn(s)
+			}
+			return nil
+		})
+		return ids, next, err
+	}
+
+	ids, next, err := stream("page", nil)
+	if err != nil || strings.Join(ids, ",") != "1,2,3" || next != "c2" {
+		t.Fatalf("page: %v %q %v", ids, next, err)
+	}
+	if gotLimit != 100 {
+		t.Errorf("default limit not sent: %d", gotLimit)
+	}
+
+	// The caller's error stops the stream, comes back unchanged and does not trip the breaker.
+	stop := errors.New("enough")
+	ids, _, err = stream("page", func(s Song) error {
+		if s.SongId == "2" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(ids) != 2 {
+		t.Fatalf("stop: %v %v", ids, err)
+	}
+	if b.State("/mcrc-sas/yinsuda/getSongList") != BreakerClosed {
+		t.Fatal("callback error tripped the breaker")
+	}
+
+	var apiErr *APIError
+	ids, _, err = stream("rejected", nil)
+	if !errors.As(err, &apiErr) || apiErr.Code != 1001 || apiErr.Message != "bad cursor" || len(ids) != 0 {
+		t.Fatalf("rejected: %v %v", ids, err)
+	}
+	ids, _, err = stream("late", nil)
+	if !errors.As(err, &apiErr) || apiErr.Code != 1001 || len(ids) != 1 {
+		t.Fatalf("late header: %v %v", ids, err)
+	}
+	if ids, _, err = stream("null", nil); err != nil || len(ids) != 0 {
+		t.Fatalf("null data: %v %v", ids, err)
+	}
+	if _, _, err = stream("malformed", nil); err == nil || !strings.Contains(err.Error(), "failed to parse base response") {
+		t.Fatalf("Expected parse error, got %v", err)
+	}
+
+	total, err := c.StreamSearchSong(ctx, &SearchSongRequest{SearchText: "x", SearchType: SearchTypeFull, Limit: 10}, func(s Song) error { return nil })
+	if err != nil || total != 42 {
+		t.Fatalf("search: %d %v", total, err)
+	}
+}
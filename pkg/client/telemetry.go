@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tracer starts spans. It is the subset of OpenTelemetry's trace.Tracer the
+// client needs, so this package stays free of the dependency; the
+// pkg/client/otel module adapts OTel tracers and meters to Tracer and
+// Metrics. The default does nothing.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is the subset of OpenTelemetry's trace.Span the client uses.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError records err and marks the span as failed.
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a span attribute. Value is a string, int, int64, bool or float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span attribute keys set by the client.
+const (
+	AttrPath            = "yinsuda.path"
+	AttrMethod          = "http.request.method"
+	AttrStatusCode      = "http.response.status_code"
+	AttrTraceId         = "yinsuda.trace_id"          // traceId request header
+	AttrResponseTraceId = "yinsuda.response_trace_id" // traceId in the response envelope
+	AttrCode            = "yinsuda.code"              // business code
+	AttrRetryCount      = "yinsuda.retry_count"       // resends by interceptors
+	AttrAppId           = "yinsuda.app_id"
+)
+
+// Metrics receives measurements from the client. Implementations turn them
+// into instruments, e.g. a latency histogram and an error counter keyed by
+// code. Methods must be safe for concurrent use. The default does nothing.
+type Metrics interface {
+	// RequestDone is called once per Do. status is 0 if no response was
+	// received; code is the business code, 0 if none was decoded.
+	RequestDone(ctx context.Context, path string, latency time.Duration, status, code int, err error)
+	// TokenRefreshed is called for every token fetch, successful or not.
+	TokenRefreshed(ctx context.Context, appId string, latency time.Duration, err error)
+	// CacheLookup is called when a cache is consulted; cache names the
+	// cache, e.g. "media".
+	CacheLookup(ctx context.Context, cache string, hit bool)
+}
+
+// MetricPathPrefix is the endpoint prefix MetricPath keeps as is.
+const MetricPathPrefix = "/mcrc-sas/yinsuda/"
+
+// MetricPath normalizes path for use as a metric label: API endpoints are
+// kept, any other path becomes "other" to bound cardinality. Spans carry
+// the full path.
+func MetricPath(path string) string {
+	if strings.HasPrefix(path, MetricPathPrefix) {
+		return path
+	}
+	return "other"
+}
+
+// ErrorClass labels a failed call for metrics, from the arguments of
+// Metrics.RequestDone: the business code if there is one, else
+// "http_<status>", "breaker_open" or "transport".
+func ErrorClass(status, code int, err error) string {
+	switch {
+	case code != 0:
+		return strconv.Itoa(code)
+	case status != 0 && status != http.StatusOK:
+		return "http_" + strconv.Itoa(status)
+	case errors.Is(err, ErrBreakerOpen):
+		return "breaker_open"
+	}
+	return "transport"
+}
+
+// MultiMetrics returns Metrics that reports to each of ms in turn. nil
+// entries are skipped.
+func MultiMetrics(ms ...Metrics) Metrics {
+	var all multiMetrics
+	for _, m := range ms {
+		switch m := m.(type) {
+		case nil, noopMetrics:
+		case multiMetrics:
+			all = append(all, m...)
+		default:
+			all = append(all, m)
+		}
+	}
+	switch len(all) {
+	case 0:
+		return noopMetrics{}
+	case 1:
+		return all[0]
+	}
+	return all
+}
+
+type multiMetrics []Metrics
+
+func (ms multiMetrics) RequestDone(ctx context.Context, path string, latency time.Duration, status, code int, err error) {
+	for _, m := range ms {
+		m.RequestDone(ctx, path, latency, status, code, err)
+	}
+}
+
+func (ms multiMetrics) TokenRefreshed(ctx context.Context, appId string, latency time.Duration, err error) {
+	for _, m := range ms {
+		m.TokenRefreshed(ctx, appId, latency, err)
+	}
+}
+
+func (ms multiMetrics) CacheLookup(ctx context.Context, cache string, hit bool) {
+	for _, m := range ms {
+		m.CacheLookup(ctx, cache, hit)
+	}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RequestDone(context.Context, string, time.Duration, int, int, error) {}
+func (noopMetrics) TokenRefreshed(context.Context, string, time.Duration, error)        {}
+func (noopMetrics) CacheLookup(context.Context, string, bool)                           {}
+
+// observe finishes the span of a Do call and reports it to Metrics.
+func (c *Client) observe(ctx context.Context, span Span, path string, start time.Time, ex *Exchange, err error) {
+	latency := time.Since(start)
+	status, code := 0, 0
+	if ex != nil {
+		span.SetAttributes(Attribute{AttrTraceId, ex.Request.Header.Get("traceId")})
+		span.SetAttributes(Attribute{AttrRetryCount, max(ex.sends-1, 0)})
+		if ex.Response != nil {
+			status = ex.Response.StatusCode
+			span.SetAttributes(Attribute{AttrStatusCode, status})
+		}
+		if ex.Envelope != nil {
+			code = ex.Envelope.Code
+			span.SetAttributes(Attribute{AttrCode, code}, Attribute{AttrResponseTraceId, ex.Envelope.TraceId})
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	c.metrics.RequestDone(ctx, path, latency, status, code, err)
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+type fakeTracer struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+type spanKey struct{}
+
+func (t *fakeTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &recordedSpan{name: name, attrs: map[string]interface{}{}}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		s.parent = parent.name
+	}
+	s.SetAttributes(attrs...)
+	t.lock.Lock()
+	t.spans = append(t.spans, s)
+	t.lock.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+type fakeMetrics struct {
+	lock     sync.Mutex
+	requests []string
+	codes    []int
+	tokens   int
+	hits     map[bool]int
+}
+
+func (m *fakeMetrics) RequestDone(ctx context.Context, path string, latency time.Duration, status, code int, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = append(m.requests, path)
+	m.codes = append(m.codes, code)
+}
+
+func (m *fakeMetrics) TokenRefreshed(ctx context.Context, appId string, latency time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.tokens++
+}
+
+func (m *fakeMetrics) CacheLookup(ctx context.Context, cache string, hit bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.hits == nil {
+		m.hits = map[bool]int{}
+	}
+	m.hits[hit]++
+}
+
+func TestClient_WithTelemetry(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"code":1001,"success":false,"traceId":"srv-trace"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Retries once on 503, the way a retry interceptor would.
+	retry := func(next Handler) Handler {
+		return func(ex *Exchange) error {
+			err := next(ex)
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == 503 {
+				err = next(ex)
+			}
+			return err
+		}
+	}
+
+	tracer := &fakeTracer{}
+	metrics := &fakeMetrics{}
+	client := NewClient("testAppId", "testAppSecret", server.URL, WithTelemetry(tracer, metrics), WithInterceptors(retry))
+
+	err := client.Do("POST", "/api/data", nil, nil, &BaseResponse{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 1001 {
+		t.Fatalf("Expected APIError 1001, got %v", err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected request and token spans, got %d", len(tracer.spans))
+	}
+	req, token := tracer.spans[0], tracer.spans[1]
+	if token.name != "yinsuda token" || token.parent != "yinsuda /api/data" || token.attrs[AttrAppId] != "testAppId" || !token.ended {
+		t.Errorf("unexpected token span %+v", token)
+	}
+	if req.name != "yinsuda /api/data" || !req.ended || req.err != err {
+		t.Errorf("unexpected request span %+v", req)
+	}
+	want := map[string]interface{}{
+		AttrPath:            "/api/data",
+		AttrMethod:          "POST",
+		AttrStatusCode:      200,
+		AttrCode:            1001,
+		AttrResponseTraceId: "srv-trace",
+		AttrRetryCount:      1,
+	}
+	for k, v := range want {
+		if req.attrs[k] != v {
+			t.Errorf("%s: Expected %v, got %v", k, v, req.attrs[k])
+		}
+	}
+	if id, _ := req.attrs[AttrTraceId].(string); id == "" {
+		t.Error("traceId attribute missing")
+	}
+
+	if len(metrics.requests) != 1 || metrics.codes[0] != 1001 || metrics.tokens != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestClient_TelemetryMediaCache(t *testing.T) {
+	metrics := &fakeMetrics{}
+	b := NewBreaker(BreakerConfig{})
+	cache := NewMemoryMediaCache(0)
+	cache.Put("1", "", &GetSongUrlResponse{})
+	client := NewClient("testAppId", "testAppSecret", "http://127.0.0.1:0", WithBreaker(b), WithMediaCache(cache), WithTelemetry(nil, metrics))
+
+	// Trip the breaker without a server.
+	for i := 0; i < 5; i++ {
+		ticket, _ := b.Allow("/mcrc-sas/yinsuda/getSongUrl")
+		ticket.Done(errors.New("connection refused"))
+	}
+	client.GetSongUrl(&GetSongUrlRequest{SongId: "1"})
+	client.GetSongUrl(&GetSongUrlRequest{SongId: "2"})
+	if metrics.hits[true] != 1 || metrics.hits[false] != 1 {
+		t.Errorf("Expected one hit and one miss, got %v", metrics.hits)
+	}
+}
+
+func TestClient_WithTelemetryComposes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"success":true}`))
+	}))
+	defer server.Close()
+
+	tracer := &fakeTracer{}
+	metrics := &fakeMetrics{}
+	client := NewClient("testAppId", "testAppSecret", server.URL,
+		WithTelemetry(tracer, nil), WithTelemetry(nil, metrics))
+	if err := client.Do("POST", "/api/data", nil, nil, &BaseResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 2 || tracer.spans[1].name != "yinsuda token" {
+		t.Errorf("Expected request and token spans, got %d", len(tracer.spans))
+	}
+	if len(metrics.requests) != 1 || metrics.tokens != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestClient_WithAddedMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"success":true}`))
+	}))
+	defer server.Close()
+
+	first, second := &fakeMetrics{}, &fakeMetrics{}
+	client := NewClient("testAppId", "testAppSecret", server.URL,
+		WithTelemetry(nil, first), WithAddedMetrics(second))
+	if err := client.Do("POST", "/api/data", nil, nil, &BaseResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range []*fakeMetrics{first, second} {
+		if len(m.requests) != 1 || m.tokens != 1 {
+			t.Errorf("metrics %d: unexpected %+v", i, m)
+		}
+	}
+	if _, ok := MultiMetrics(nil, noopMetrics{}, first).(*fakeMetrics); !ok {
+		t.Error("MultiMetrics of one should return it unwrapped")
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	cases := []struct {
+		status, code int
+		err          error
+		want         string
+	}{
+		{200, 1001, errors.New("x"), "1001"},
+		{502, 0, errors.New("x"), "http_502"},
+		{0, 0, &BreakerOpenError{}, "breaker_open"},
+		{0, 0, errors.New("dial tcp"), "transport"},
+	}
+	for _, tc := range cases {
+		if got := ErrorClass(tc.status, tc.code, tc.err); got != tc.want {
+			t.Errorf("ErrorClass(%d, %d, %v) = %q, want %q", tc.status, tc.code, tc.err, got, tc.want)
+		}
+	}
+	if got := MetricPath("/mcrc-sas/yinsuda/getSongList"); got != "/mcrc-sas/yinsuda/getSongList" {
+		t.Errorf("Expected endpoint path kept, got %q", got)
+	}
+	if got := MetricPath("/elsewhere"); got != "other" {
+		t.Errorf("Expected other, got %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validator is implemented by request types. Call runs Validate before
+// signing and sending unless the client was built with WithValidation(false).
+type Validator interface {
+	Validate() error
+}
+
+// FieldError describes one invalid field. Field is the JSON name.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError is returned by Validate, and by the endpoint methods
+// before anything is sent, when a request has invalid fields.
+type ValidationError struct {
+	Request string // request type, e.g. "GetSongUrlRequest"
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.String()
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Request, strings.Join(parts, "; "))
+}
+
+// Field returns the error for field, if any.
+func (e *ValidationError) Field(field string) (FieldError, bool) {
+	for _, f := range e.Fields {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return FieldError{}, false
+}
+
+// fieldErrors collects FieldErrors for one request.
+type fieldErrors struct {
+	request string
+	fields  []FieldError
+}
+
+func (v *fieldErrors) add(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *fieldErrors) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "required")
+	}
+}
+
+func (v *fieldErrors) nonNegative(field string, n int) {
+	if n < 0 {
+		v.add(field, "must not be negative, got %d", n)
+	}
+}
+
+func (v *fieldErrors) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Request: v.request, Fields: v.fields}
+}
+
+func (r *GetSongListRequest) Validate() error {
+	v := fieldErrors{request: "GetSongListRequest"}
+	v.nonNegative("limit", r.Limit)
+	v.nonNegative("offset", r.Offset)
+	return v.err()
+}
+
+// Validate checks the id list is non-empty and has no blank ids. It does not
+// limit the batch size; see WithMaxSongInfoIds.
+func (r *GetSongInfoRequest) Validate() error {
+	return r.validate(0)
+}
+
+// validate is Validate with a batch limit; maxIds <= 0 means none.
+func (r *GetSongInfoRequest) validate(maxIds int) error {
+	v := fieldErrors{request: "GetSongInfoRequest"}
+	if strings.TrimSpace(r.SongIdListStr) == "" {
+		v.add("songIdListStr", "at least one song id is required")
+		return v.err()
+	}
+	ids := strings.Split(r.SongIdListStr, ",")
+	for i, id := range ids {
+		if strings.TrimSpace(id) == "" {
+			v.add("songIdListStr", "id %d is empty", i+1)
+			break
+		}
+	}
+	if maxIds > 0 && len(ids) > maxIds {
+		v.add("songIdListStr", "at most %d ids per request, got %d", maxIds, len(ids))
+	}
+	return v.err()
+}
+
+func (r *GetSongUrlRequest) Validate() error {
+	v := fieldErrors{request: "GetSongUrlRequest"}
+	v.required("songId", r.SongId)
+	return v.err()
+}
+
+// Validate checks the text, type, status and paging fields. It does not cap
+// Limit; see WithMaxSearchLimit.
+func (r *SearchSongRequest) Validate() error {
+	return r.validate(0)
+}
+
+// validate is Validate with a cap on Limit; maxLimit <= 0 means none.
+func (r *SearchSongRequest) validate(maxLimit int) error {
+	v := fieldErrors{request: "SearchSongRequest"}
+	v.required("searchText", r.SearchText)
+	if !ValidSearchType(r.SearchType) {
+		v.add("searchType", "must be 1 (full), 2 (song name) or 3 (artist name), got %d", r.SearchType)
+	}
+	if _, ok := r.Filter(); !ok {
+		v.add("status", "must be 0 or 1, got %d", r.Status)
+	}
+	v.nonNegative("offset", r.Offset)
+	if r.Limit < 1 {
+		v.add("limit", "must be at least 1, got %d", r.Limit)
+	} else if maxLimit > 0 && r.Limit > maxLimit {
+		v.add("limit", "must be at most %d, got %d", maxLimit, r.Limit)
+	}
+	return v.err()
+}
+
+func (r *PageRequest) Validate() error {
+	v := fieldErrors{request: "PageRequest"}
+	v.nonNegative("offset", r.Offset)
+	if r.Length < 1 {
+		v.add("length", "must be at least 1, got %d", r.Length)
+	}
+	return v.err()
+}
+
+func (r *QuerySongListDetailRequest) Validate() error {
+	v := fieldErrors{request: "QuerySongListDetailRequest"}
+	v.required("code", r.Code)
+	return v.err()
+}
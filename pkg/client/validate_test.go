@@ -0,0 +1,112 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	noStatus := &SearchSongRequest{SearchText: "晴天", SearchType: SearchTypeFull, Limit: 20}
+	badStatus := &SearchSongRequest{SearchText: "晴天", SearchType: SearchTypeFull, Limit: 20}
+	badStatus.SetStatus(2)
+
+	cases := []struct {
+		name   string
+		req    Validator
+		fields []string // fields expected to be reported, in order
+	}{
+		{"song list ok", &GetSongListRequest{Limit: 100}, nil},
+		{"song list negative", &GetSongListRequest{Limit: -1, Offset: -1}, []string{"limit", "offset"}},
+		{"info ok", &GetSongInfoRequest{SongIdListStr: "1,2"}, nil},
+		{"info empty", &GetSongInfoRequest{}, []string{"songIdListStr"}},
+		{"info blank id", &GetSongInfoRequest{SongIdListStr: "1,,2"}, []string{"songIdListStr"}},
+		{"info no default batch limit", &GetSongInfoRequest{SongIdListStr: strings.TrimSuffix(strings.Repeat("1,", 500), ",")}, nil},
+		{"url ok", &GetSongUrlRequest{SongId: "1"}, nil},
+		{"url empty", &GetSongUrlRequest{SongId: " "}, []string{"songId"}},
+		{"search ok", noStatus, nil},
+		{"search bad", &SearchSongRequest{SearchType: 4, Offset: -1, Limit: 0}, []string{"searchText", "searchType", "offset", "limit"}},
+		{"search status", badStatus, []string{"status"}},
+		{"page ok", &PageRequest{Length: 10}, nil},
+		{"page zero length", &PageRequest{}, []string{"length"}},
+		{"detail empty", &QuerySongListDetailRequest{}, []string{"code"}},
+	}
+	for _, tc := range cases {
+		err := tc.req.Validate()
+		if tc.fields == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		var vErr *ValidationError
+		if !errors.As(err, &vErr) {
+			t.Errorf("%s: Expected ValidationError, got %v", tc.name, err)
+			continue
+		}
+		var got []string
+		for _, f := range vErr.Fields {
+			got = append(got, f.Field)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.fields, ",") {
+			t.Errorf("%s: Expected fields %v, got %v (%v)", tc.name, tc.fields, got, err)
+		}
+	}
+}
+
+func TestClient_Validation(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"0","success":true,"data":{"accessToken":"tok","expire":3600}}`))
+	})
+	mux.HandleFunc("/mcrc-sas/yinsuda/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"code":400,"success":false,"message":"songId required"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := NewClient("testAppId", "testAppSecret", server.URL)
+	_, err := c.GetSongUrl(&GetSongUrlRequest{})
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) || vErr.Request != "GetSongUrlRequest" {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if f, ok := vErr.Field("songId"); !ok || f.Message != "required" {
+		t.Errorf("unexpected field error %+v", vErr.Fields)
+	}
+	if err.Error() != "invalid GetSongUrlRequest: songId: required" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if _, err := c.GetSongInfo(nil); !errors.As(err, &vErr) {
+		t.Errorf("Expected ValidationError for empty id list, got %v", err)
+	}
+	if _, err := c.QuerySongListPage(&PageRequest{}); !errors.As(err, &vErr) {
+		t.Errorf("Expected ValidationError for zero length, got %v", err)
+	}
+	capped := NewClient("testAppId", "testAppSecret", server.URL, WithMaxSongInfoIds(2))
+	if _, err := capped.GetSongInfo([]string{"1", "2", "3"}); !errors.As(err, &vErr) || !strings.Contains(err.Error(), "at most 2 ids") {
+		t.Errorf("Expected batch limit ValidationError, got %v", err)
+	}
+	capped = NewClient("testAppId", "testAppSecret", server.URL, WithMaxSearchLimit(50))
+	if _, err := capped.SearchSong(&SearchSongRequest{SearchText: "a", SearchType: SearchTypeFull, Limit: 51}); !errors.As(err, &vErr) || !strings.Contains(err.Error(), "at most 50") {
+		t.Errorf("Expected search limit ValidationError, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Fatalf("invalid requests reached the server %d times", n)
+	}
+
+	// Disabled: the server decides.
+	lax := NewClient("testAppId", "testAppSecret", server.URL, WithValidation(false))
+	var apiErr *APIError
+	if _, err := lax.GetSongUrl(&GetSongUrlRequest{}); !errors.As(err, &apiErr) || apiErr.Code != 400 {
+		t.Fatalf("Expected server APIError, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 call with validation off, got %d", n)
+	}
+}
@@ -0,0 +1,228 @@
+package client
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SecretLookup returns the appSecret for appId. It should return an error
+// for unknown apps.
+type SecretLookup func(appId string) (string, error)
+
+// VerifyReason classifies why a signed request was rejected.
+type VerifyReason string
+
+const (
+	ReasonMissingHeader     VerifyReason = "missing_header"
+	ReasonUnknownApp        VerifyReason = "unknown_app"
+	ReasonBadTimestamp      VerifyReason = "bad_timestamp"
+	ReasonTimestampSkew     VerifyReason = "timestamp_skew"
+	ReasonUnsupportedMethod VerifyReason = "unsupported_sign_method"
+	ReasonBodyUnreadable    VerifyReason = "body_unreadable"
+	ReasonSignMismatch      VerifyReason = "sign_mismatch"
+)
+
+// VerifyError is returned when a request fails verification.
+type VerifyError struct {
+	Reason VerifyReason
+	Detail string
+	Err    error // underlying error, if any
+}
+
+func (e *VerifyError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("verify failed (%s): %s: %v", e.Reason, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("verify failed (%s): %s", e.Reason, e.Detail)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultMaxSkew is the timestamp window used by VerifyRequest.
+const DefaultMaxSkew = 5 * time.Minute
+
+// defaultMaxVerifyBody bounds how much body a Verifier buffers.
+const defaultMaxVerifyBody = 10 << 20
+
+// Verifier checks requests signed the way Client.Do signs them.
+type Verifier struct {
+	Lookup SecretLookup
+	// MaxSkew is how far the timestamp header may be from Now, either way.
+	// Defaults to DefaultMaxSkew; negative disables the check.
+	MaxSkew time.Duration
+	// Location is the zone the timestamp header is interpreted in. The header
+	// carries no zone; Client.Do formats it in time.Local, the default.
+	Location *time.Location
+	// MaxBodySize caps the buffered body, in bytes. Defaults to 10 MiB.
+	MaxBodySize int64
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+	// Replay, if set, additionally rejects stale timestamps and reused
+	// traceIds. It only runs once the signature has been verified.
+	Replay *ReplayGuard
+}
+
+// VerifyRequest verifies r with DefaultMaxSkew. See Verifier.Verify.
+func VerifyRequest(r *http.Request, lookup SecretLookup) error {
+	return (&Verifier{Lookup: lookup}).Verify(r)
+}
+
+// Verify rebuilds SignParams from the request headers, hashes the body with
+// the Signer registered for the signMethod/signVersion headers and compares
+// the sign header in constant time. The body is buffered and
+// restored, so handlers after Verify can still read it.
+// Failures are returned as *VerifyError.
+func (v *Verifier) Verify(r *http.Request) error {
+	h := r.Header
+	params := SignParams{
+		AppId:       h.Get("appId"),
+		AccessToken: h.Get("accessToken"),
+		Timestamp:   h.Get("timestamp"),
+		SignMethod:  h.Get("signMethod"),
+		TraceId:     h.Get("traceId"),
+		Source:      h.Get("source"),
+	}
+	sign := h.Get("sign")
+
+	// 1. Required headers
+	required := [][2]string{
+		{"appId", params.AppId}, {"timestamp", params.Timestamp}, {"signMethod", params.SignMethod}, {"sign", sign},
+	}
+	for _, kv := range required {
+		if kv[1] == "" {
+			return &VerifyError{Reason: ReasonMissingHeader, Detail: kv[0]}
+		}
+	}
+	signer, ok := LookupSigner(params.SignMethod, h.Get("signVersion"))
+	if !ok {
+		return &VerifyError{Reason: ReasonUnsupportedMethod, Detail: params.SignMethod + " " + h.Get("signVersion")}
+	}
+
+	// 2. Timestamp window
+	if err := v.checkTimestamp(params.Timestamp); err != nil {
+		return err
+	}
+
+	// 3. Secret
+	secret, err := v.Lookup(params.AppId)
+	if err != nil {
+		return &VerifyError{Reason: ReasonUnknownApp, Detail: params.AppId, Err: err}
+	}
+
+	// 4. Body, restored for downstream handlers
+	body, err := v.readBody(r)
+	if err != nil {
+		return &VerifyError{Reason: ReasonBodyUnreadable, Detail: "reading body", Err: err}
+	}
+
+	// 5. Compare
+	// Client.Do signs the query exactly as sent. Signers that canonicalise
+	// independently of the URL they send are accepted via the sorted form.
+	match := func(rawQuery string) bool {
+		want := signer.Sign(params, body, r.URL.Path, rawQuery, secret)
+		return subtle.ConstantTimeCompare([]byte(want), []byte(sign)) == 1
+	}
+	if !match(r.URL.RawQuery) {
+		canonical := r.URL.Query().Encode()
+		if canonical == r.URL.RawQuery || !match(canonical) {
+			return &VerifyError{Reason: ReasonSignMismatch, Detail: "sign does not match request"}
+		}
+	}
+
+	// 6. Replay
+	if v.Replay != nil {
+		return v.Replay.Check(r)
+	}
+	return nil
+}
+
+func (v *Verifier) checkTimestamp(ts string) error {
+	loc := v.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation("20060102150405", ts, loc)
+	if err != nil {
+		return &VerifyError{Reason: ReasonBadTimestamp, Detail: ts, Err: err}
+	}
+
+	maxSkew := v.MaxSkew
+	if maxSkew == 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	if maxSkew < 0 {
+		return nil
+	}
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if skew := now().Sub(t); skew > maxSkew || skew < -maxSkew {
+		return &VerifyError{Reason: ReasonTimestampSkew, Detail: fmt.Sprintf("timestamp %s is %s from now", ts, skew.Round(time.Second))}
+	}
+	return nil
+}
+
+func (v *Verifier) readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	limit := v.MaxBodySize
+	if limit <= 0 {
+		limit = defaultMaxVerifyBody
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("body exceeds %d bytes", limit)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+// Middleware returns a handler that verifies each request before passing it to next.
+// Rejected requests get HTTP 401 with a BaseResponse envelope naming the reason.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			writeVerifyError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// VerifyMiddleware wraps next with a Verifier using lookup and the defaults.
+func VerifyMiddleware(lookup SecretLookup, next http.Handler) http.Handler {
+	return (&Verifier{Lookup: lookup}).Middleware(next)
+}
+
+func writeVerifyError(w http.ResponseWriter, err error) {
+	reason := "verify_failed"
+	var vErr *VerifyError
+	if errors.As(err, &vErr) {
+		reason = string(vErr.Reason)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(BaseResponse{
+		Code:    http.StatusUnauthorized,
+		Message: reason,
+		Msg:     err.Error(),
+		Success: false,
+	})
+}
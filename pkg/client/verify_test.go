@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifier_Middleware(t *testing.T) {
+	lookup := func(appId string) (string, error) {
+		if appId == "testAppId" {
+			return "testAppSecret", nil
+		}
+		return "", fmt.Errorf("unknown app %s", appId)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(TokenResponse{Code: "0", Success: true, Data: TokenData{AccessToken: "tok", Expire: 3600}})
+	})
+	mux.Handle("/api/data", VerifyMiddleware(lookup, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body must still be readable after verification.
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(BaseResponse{Code: 0, Success: true, Data: string(body)})
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// A request signed by Client.Do passes, query string included.
+	client := NewClient("testAppId", "testAppSecret", server.URL)
+	var result BaseResponse
+	query := url.Values{"q": {"住在心里"}}
+	if err := client.Do("POST", "/api/data", query, map[string]string{"k": "v"}, &result); err != nil {
+		t.Fatalf("signed request rejected: %v", err)
+	}
+	if result.Data != `{"k":"v"}` {
+		t.Errorf("handler saw body %v", result.Data)
+	}
+
+	// Signed with the wrong secret.
+	bad := NewClient("testAppId", "wrongSecret", server.URL)
+	err := bad.Do("POST", "/api/data", nil, map[string]string{"k": "v"}, &result)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || !strings.Contains(apiErr.Body, string(ReasonSignMismatch)) {
+		t.Errorf("expected sign_mismatch rejection, got %v", err)
+	}
+}
+
+func TestVerifier_Reasons(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := &Verifier{
+		Lookup:   func(string) (string, error) { return "s", nil },
+		Location: time.UTC,
+		Now:      func() time.Time { return now },
+	}
+
+	signed := func(ts, body string) *http.Request {
+		params := SignParams{AppId: "a", AccessToken: "t", Timestamp: ts, SignMethod: "md5", TraceId: "x"}
+		r := httptest.NewRequest("POST", "/p?b=2&a=1", strings.NewReader(body))
+		r.Header.Set("appId", "a")
+		r.Header.Set("accessToken", "t")
+		r.Header.Set("timestamp", ts)
+		r.Header.Set("signMethod", "md5")
+		r.Header.Set("traceId", "x")
+		r.Header.Set("sign", CalculateSign(params, []byte(body), "/p", url.Values{"a": {"1"}, "b": {"2"}}, "s"))
+		return r
+	}
+
+	if err := v.Verify(signed("20240501120100", `{}`)); err != nil {
+		t.Errorf("valid request rejected: %v", err)
+	}
+
+	tampered := signed("20240501120000", `{}`)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"x":1}`))
+	noSign := signed("20240501120000", `{}`)
+	noSign.Header.Del("sign")
+
+	cases := []struct {
+		name string
+		req  *http.Request
+		want VerifyReason
+	}{
+		{"stale", signed("20240501115000", `{}`), ReasonTimestampSkew},
+		{"future", signed("20240501121000", `{}`), ReasonTimestampSkew},
+		{"garbled timestamp", signed("2024-05-01", `{}`), ReasonBadTimestamp},
+		{"tampered body", tampered, ReasonSignMismatch},
+		{"missing sign", noSign, ReasonMissingHeader},
+	}
+	for _, tc := range cases {
+		err := v.Verify(tc.req)
+		var vErr *VerifyError
+		if !errors.As(err, &vErr) || vErr.Reason != tc.want {
+			t.Errorf("%s: expected %s, got %v", tc.name, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package export dumps playlists and rankings, with their songs resolved via
+// GetSongInfo, to JSON, CSV or extended M3U.
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Kind selects playlists or rankings.
+type Kind string
+
+const (
+	KindSongList    Kind = "playlists"
+	KindRankingList Kind = "rankings"
+)
+
+// Source is the subset of *client.Client used by the Exporter.
+type Source interface {
+	QuerySongListPageContext(ctx context.Context, req *client.PageRequest) (*client.QuerySongListResponse, error)
+	QuerySongListDetailContext(ctx context.Context, code string) (*client.QuerySongListDetailResponse, error)
+	QueryRankingListPageContext(ctx context.Context, req *client.PageRequest) (*client.QuerySongListResponse, error)
+	QueryRankingListDetailContext(ctx context.Context, code string) (*client.QuerySongListDetailResponse, error)
+	GetSongInfoContext(ctx context.Context, songIds []string) (*client.GetSongInfoResponse, error)
+}
+
+// List is one exported playlist or ranking.
+// Songs keeps the list order; a song GetSongInfo did not return has only its SongId set.
+type List struct {
+	Code        string        `json:"code"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	ImgUrl      string        `json:"imgUrl"`
+	Status      int           `json:"status"`
+	Songs       []client.Song `json:"songs"`
+}
+
+// Exporter walks every playlist or ranking and resolves its songs.
+type Exporter struct {
+	src Source
+
+	// PageSize is the page length for the list walk. Defaults to 50.
+	PageSize int
+	// BatchSize caps the number of ids per GetSongInfo call. Defaults to 50.
+	BatchSize int
+}
+
+func NewExporter(src Source) *Exporter {
+	return &Exporter{src: src, PageSize: 50, BatchSize: 50}
+}
+
+// Collect fetches every list of the given kind with its songs.
+// Songs shared between lists are fetched once.
+func (e *Exporter) Collect(ctx context.Context, kind Kind) ([]List, error) {
+	page := e.src.QuerySongListPageContext
+	detail := e.src.QuerySongListDetailContext
+	switch kind {
+	case KindSongList:
+	case KindRankingList:
+		page = e.src.QueryRankingListPageContext
+		detail = e.src.QueryRankingListDetailContext
+	default:
+		return nil, fmt.Errorf("unknown export kind %q", kind)
+	}
+
+	// 1. Walk the pages
+	var infos []client.PlayListInfo
+	for offset := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := page(ctx, &client.PageRequest{Offset: offset, Length: e.PageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s at offset %d: %w", kind, offset, err)
+		}
+		infos = append(infos, resp.List...)
+		offset += len(resp.List)
+		if len(resp.List) == 0 || offset >= resp.Total {
+			break
+		}
+	}
+
+	// 2. Fetch details
+	lists := make([]List, 0, len(infos))
+	var ids []string
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		d, err := detail(ctx, info.Code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s %s: %w", kind, info.Code, err)
+		}
+		l := List{Code: info.Code, Title: d.Title, Description: d.Description, ImgUrl: d.ImgUrl, Status: d.Status}
+		if l.ImgUrl == "" {
+			l.ImgUrl = info.ImgUrl
+		}
+		for _, s := range d.SongList {
+			l.Songs = append(l.Songs, client.Song{SongId: s.SongId})
+			if !seen[s.SongId] {
+				seen[s.SongId] = true
+				ids = append(ids, s.SongId)
+			}
+		}
+		lists = append(lists, l)
+	}
+
+	// 3. Resolve songs
+	songs, err := e.resolve(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lists {
+		for j, s := range lists[i].Songs {
+			if full, ok := songs[s.SongId]; ok {
+				lists[i].Songs[j] = full
+			}
+		}
+	}
+	return lists, nil
+}
+
+func (e *Exporter) resolve(ctx context.Context, ids []string) (map[string]client.Song, error) {
+	batch := e.BatchSize
+	if batch <= 0 {
+		batch = 50
+	}
+	songs := make(map[string]client.Song, len(ids))
+	for start := 0; start < len(ids); start += batch {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+batch, len(ids))
+		resp, err := e.src.GetSongInfoContext(ctx, ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("getSongInfo failed: %w", err)
+		}
+		for _, s := range resp.SongList {
+			songs[s.SongId] = s
+		}
+	}
+	return songs, nil
+}
@@ -0,0 +1,95 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+type fakeSource struct {
+	infoCalls int
+}
+
+func (f *fakeSource) QuerySongListPageContext(ctx context.Context, req *client.PageRequest) (*client.QuerySongListResponse, error) {
+	all := []client.PlayListInfo{{Code: "P1", Title: "Road\nTrip"}, {Code: "P2", Title: "Empty"}}
+	end := min(req.Offset+req.Length, len(all))
+	return &client.QuerySongListResponse{Total: len(all), List: all[req.Offset:end]}, nil
+}
+
+func (f *fakeSource) QuerySongListDetailContext(ctx context.Context, code string) (*client.QuerySongListDetailResponse, error) {
+	d := &client.QuerySongListDetailResponse{Code: code, Title: code}
+	if code == "P1" {
+		for _, id := range []string{"S1", "S2", "GONE"} {
+			d.SongList = append(d.SongList, struct {
+				SongId string `json:"songId"`
+			}{id})
+		}
+	}
+	return d, nil
+}
+
+func (f *fakeSource) QueryRankingListPageContext(ctx context.Context, req *client.PageRequest) (*client.QuerySongListResponse, error) {
+	return &client.QuerySongListResponse{}, nil
+}
+
+func (f *fakeSource) QueryRankingListDetailContext(ctx context.Context, code string) (*client.QuerySongListDetailResponse, error) {
+	return nil, nil
+}
+
+func (f *fakeSource) GetSongInfoContext(ctx context.Context, songIds []string) (*client.GetSongInfoResponse, error) {
+	f.infoCalls++
+	return &client.GetSongInfoResponse{SongList: []client.Song{
+		{SongId: "S1", SongName: "晴天", Duration: 269, Status: 1, ArtistList: []client.Artist{{ArtistName: "周杰伦"}}},
+		{SongId: "S2", SongName: "Hello, World", Duration: 180, Status: 0, Album: client.Album{AlbumName: "A"}},
+	}}, nil
+}
+
+func TestExporter(t *testing.T) {
+	src := &fakeSource{}
+	e := NewExporter(src)
+	e.PageSize = 1
+
+	lists, err := e.Collect(context.Background(), KindSongList)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(lists) != 2 || len(lists[0].Songs) != 3 || lists[0].Songs[0].SongName != "晴天" {
+		t.Fatalf("unexpected lists: %+v", lists)
+	}
+	if lists[0].Songs[2].SongId != "GONE" || src.infoCalls != 1 {
+		t.Errorf("missing song should keep its id and position")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatM3U, lists[:1]); err != nil {
+		t.Fatalf("Write m3u failed: %v", err)
+	}
+	wantM3U := "#EXTM3U\n#PLAYLIST:P1\n" +
+		"#EXTINF:269,周杰伦 - 晴天\nyinsuda://song/S1\n" +
+		"#EXTINF:180,Hello, World\nyinsuda://song/S2\n" +
+		"#EXTINF:-1,\nyinsuda://song/GONE\n"
+	if buf.String() != wantM3U {
+		t.Errorf("m3u mismatch:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	enc := Encoder{Format: FormatM3U, SongLocation: func(s client.Song) string { return "/music/" + s.SongId + ".mp3" }}
+	if err := enc.Write(&buf, lists[:1]); err != nil {
+		t.Fatalf("Encoder.Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "\n/music/S1.mp3\n") || strings.Contains(buf.String(), "yinsuda://") {
+		t.Errorf("SongLocation not used:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatCSV, lists[:1]); err != nil {
+		t.Fatalf("Write csv failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[2] != `P1,P1,2,S2,"Hello, World",,A,180,0` {
+		t.Errorf("csv mismatch:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,173 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatM3U  Format = "m3u"
+)
+
+// ParseFormat accepts "json", "csv", "m3u" or "m3u8".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "m3u", "m3u8":
+		return FormatM3U, nil
+	}
+	return "", fmt.Errorf("unknown export format %q", s)
+}
+
+// Ext returns the file extension, including the dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// DefaultSongLocation is the M3U entry location of a song unless an Encoder
+// sets SongLocation. Media URLs from GetSongUrl expire, so it is a stable,
+// resolvable-later reference.
+func DefaultSongLocation(s client.Song) string {
+	return "yinsuda://song/" + s.SongId
+}
+
+// Encoder writes lists in one format.
+type Encoder struct {
+	Format Format
+	// SongLocation returns the M3U entry location of a song. nil means
+	// DefaultSongLocation.
+	SongLocation func(client.Song) string
+}
+
+// Write encodes lists to w in the given format with the default Encoder
+// settings.
+func Write(w io.Writer, format Format, lists []List) error {
+	return Encoder{Format: format}.Write(w, lists)
+}
+
+// WriteDir writes one file per list into dir with the default Encoder
+// settings. See Encoder.WriteDir.
+func WriteDir(dir string, format Format, lists []List) error {
+	return Encoder{Format: format}.WriteDir(dir, lists)
+}
+
+// Write encodes lists to w.
+func (e Encoder) Write(w io.Writer, lists []List) error {
+	switch format := e.Format; format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(lists)
+	case FormatCSV:
+		return writeCSV(w, lists)
+	case FormatM3U:
+		location := e.SongLocation
+		if location == nil {
+			location = DefaultSongLocation
+		}
+		return writeM3U(w, lists, location)
+	}
+	return fmt.Errorf("unknown export format %q", e.Format)
+}
+
+// WriteDir writes one file per list into dir, named <code><ext>.
+func (e Encoder) WriteDir(dir string, lists []List) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, l := range lists {
+		name := strings.NewReplacer("/", "_", "\\", "_").Replace(l.Code) + e.Format.Ext()
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		err = e.Write(f, []List{l})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func artists(s client.Song) string {
+	names := make([]string, 0, len(s.ArtistList))
+	for _, a := range s.ArtistList {
+		names = append(names, a.ArtistName)
+	}
+	return strings.Join(names, "/")
+}
+
+func writeCSV(w io.Writer, lists []List) error {
+	cw := csv.NewWriter(w)
+	header := []string{"listCode", "listTitle", "position", "songId", "song", "artists", "album", "duration", "status"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, l := range lists {
+		for i, s := range l.Songs {
+			err := cw.Write([]string{
+				l.Code,
+				l.Title,
+				strconv.Itoa(i + 1),
+				s.SongId,
+				s.SongName,
+				artists(s),
+				s.Album.AlbumName,
+				strconv.Itoa(s.Duration),
+				strconv.Itoa(s.Status),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeM3U writes extended M3U with location as the entry of each song.
+// Lines cannot carry newlines, so titles are flattened.
+func writeM3U(w io.Writer, lists []List, location func(client.Song) string) error {
+	bw := bufio.NewWriter(w)
+	flat := strings.NewReplacer("\r", " ", "\n", " ")
+
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, l := range lists {
+		fmt.Fprintf(bw, "#PLAYLIST:%s\n", flat.Replace(l.Title))
+		for _, s := range l.Songs {
+			title := s.SongName
+			if a := artists(s); a != "" {
+				title = a + " - " + title
+			}
+			// #EXTINF duration is in seconds, as is Song.Duration; -1 means unknown.
+			duration := s.Duration
+			if duration <= 0 {
+				duration = -1
+			}
+			fmt.Fprintf(bw, "#EXTINF:%d,%s\n", duration, flat.Replace(title))
+			fmt.Fprintln(bw, location(s))
+		}
+	}
+	return bw.Flush()
+}
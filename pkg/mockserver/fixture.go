@@ -0,0 +1,122 @@
+package mockserver
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Fixture is the data a Server serves.
+type Fixture struct {
+	// Apps maps appId to appSecret. Only these credentials get tokens.
+	Apps map[string]string
+	// TokenTTL is the "expire" value, in seconds, of issued tokens. Defaults to 900.
+	TokenTTL int
+
+	Songs     []client.Song
+	Playlists []client.PlayListDetail
+	Rankings  []client.PlayListDetail
+	// Media overrides GetSongUrl results per SongId. Songs without an entry
+	// get one synthesized mp3 pointing at the server.
+	Media map[string][]client.MediaInfo
+}
+
+// Test credentials present in DefaultFixture and GenerateFixture.
+const (
+	TestAppId     = "1009232"
+	TestAppSecret = "mock-secret"
+)
+
+func song(id, name, artist, album, lang string, duration, status int) client.Song {
+	s := client.Song{
+		SongId:     id,
+		SongName:   name,
+		Duration:   duration,
+		Status:     status,
+		Language:   lang,
+		Album:      client.Album{AlbumId: "AL-" + album, AlbumName: album},
+		ArtistList: []client.Artist{{ArtistId: "AR-" + artist, ArtistName: artist}},
+	}
+	if status == 0 {
+		s.TakeDownReason = "license expired"
+	}
+	return s
+}
+
+func detail(code, title string, ids ...string) client.PlayListDetail {
+	d := client.PlayListDetail{Code: code, Title: title, Description: title, Status: 1,
+		ImgUrl: "https://img.example.com/" + code + ".jpg"}
+	for _, id := range ids {
+		d.SongList = append(d.SongList, struct {
+			SongId string `json:"songId"`
+		}{id})
+	}
+	return d
+}
+
+// DefaultFixture is a small hand-written catalog: ten songs (one taken down),
+// two playlists and one ranking.
+func DefaultFixture() Fixture {
+	return Fixture{
+		Apps: map[string]string{TestAppId: TestAppSecret},
+		Songs: []client.Song{
+			song("S001", "住在心里", "林俊杰", "新地球", "国语", 251, 1),
+			song("S002", "晴天", "周杰伦", "叶惠美", "国语", 269, 1),
+			song("S003", "七里香", "周杰伦", "七里香", "国语", 299, 1),
+			song("S004", "江南", "林俊杰", "第二天堂", "国语", 267, 1),
+			song("S005", "海阔天空", "Beyond", "乐与怒", "粤语", 326, 1),
+			song("S006", "光辉岁月", "Beyond", "命运派对", "粤语", 298, 1),
+			song("S007", "Yesterday", "The Beatles", "Help!", "英语", 125, 1),
+			song("S008", "Let It Be", "The Beatles", "Let It Be", "英语", 243, 1),
+			song("S009", "稻香", "周杰伦", "魔杰座", "国语", 223, 1),
+			song("S010", "红豆", "王菲", "唱游", "国语", 257, 0),
+		},
+		Playlists: []client.PlayListDetail{
+			detail("PL001", "华语经典", "S002", "S003", "S004", "S009"),
+			detail("PL002", "粤语金曲", "S005", "S006"),
+		},
+		Rankings: []client.PlayListDetail{
+			detail("RK001", "热歌榜", "S002", "S001", "S005", "S007", "S009"),
+		},
+	}
+}
+
+// GenerateFixture builds a deterministic catalog of n songs and
+// playlists/rankings of up to 20 songs each, for pagination tests.
+func GenerateFixture(n int, seed int64) Fixture {
+	rng := rand.New(rand.NewSource(seed))
+	f := Fixture{Apps: map[string]string{TestAppId: TestAppSecret}}
+
+	langs := []string{"国语", "粤语", "英语"}
+	for i := 0; i < n; i++ {
+		status := 1
+		if rng.Intn(20) == 0 {
+			status = 0
+		}
+		f.Songs = append(f.Songs, song(
+			fmt.Sprintf("S%06d", i),
+			fmt.Sprintf("Song %d", i),
+			fmt.Sprintf("Artist %d", rng.Intn(n/10+1)),
+			fmt.Sprintf("Album %d", rng.Intn(n/5+1)),
+			langs[rng.Intn(len(langs))],
+			120+rng.Intn(240),
+			status,
+		))
+	}
+
+	pick := func() []string {
+		var ids []string
+		for j := rng.Intn(20) + 1; j > 0 && n > 0; j-- {
+			ids = append(ids, f.Songs[rng.Intn(n)].SongId)
+		}
+		return ids
+	}
+	for i := 0; i < n/10+1; i++ {
+		f.Playlists = append(f.Playlists, detail(fmt.Sprintf("PL%04d", i), fmt.Sprintf("Playlist %d", i), pick()...))
+	}
+	for i := 0; i < n/50+1; i++ {
+		f.Rankings = append(f.Rankings, detail(fmt.Sprintf("RK%04d", i), fmt.Sprintf("Ranking %d", i), pick()...))
+	}
+	return f
+}
@@ -0,0 +1,542 @@
+// Package mockserver is an offline stand-in for the Yinsuda API, for
+// integration tests that should not need real credentials.
+//
+// It serves /oauth2/token and every /mcrc-sas/yinsuda/* endpoint from a
+// Fixture, validates the sign header with the registered client.Signer
+// (client.CalculateSign for the default md5 scheme), and implements
+// the real pagination semantics (queryInfo cursor for getSongList, offset/limit
+// or offset/length elsewhere). Errors and latency can be injected per endpoint,
+// and Notify pushes notification webhooks to a target URL.
+//
+//	srv := mockserver.New(mockserver.DefaultFixture())
+//	defer srv.Close()
+//	c := client.NewClient(mockserver.TestAppId, mockserver.TestAppSecret, srv.URL)
+package mockserver
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Business codes returned by the mock. The real platform's codes are not
+// published; tests should rely on Success=false rather than specific values.
+const (
+	CodeBadRequest   = 400
+	CodeInvalidToken = 401
+	CodeInvalidSign  = 403
+	CodeNotFound     = 404
+	CodeInternal     = 500
+)
+
+// Endpoint paths served by the mock.
+const (
+	PathToken                  = "/oauth2/token"
+	PathGetSongList            = "/mcrc-sas/yinsuda/getSongList"
+	PathGetSongInfo            = "/mcrc-sas/yinsuda/getSongInfo"
+	PathGetSongUrl             = "/mcrc-sas/yinsuda/getSongUrl"
+	PathSearchSong             = "/mcrc-sas/yinsuda/searchSong"
+	PathQuerySongListPage      = "/mcrc-sas/yinsuda/querySongListPage"
+	PathQuerySongListDetail    = "/mcrc-sas/yinsuda/querySongListDetail"
+	PathQueryRankingListPage   = "/mcrc-sas/yinsuda/queryRankingListPage"
+	PathQueryRankingListDetail = "/mcrc-sas/yinsuda/queryRankingListDetail"
+)
+
+// Fault is an injected failure. With StatusCode set, the mock answers with that
+// HTTP status and Body; otherwise it answers 200 with a failed envelope carrying Code.
+type Fault struct {
+	StatusCode int
+	Body       string
+	Code       int
+	Message    string
+	// Times limits how many requests fail; 0 means until cleared.
+	Times int
+}
+
+// Server is a running mock. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL to pass to client.NewClient.
+	URL string
+
+	srv    *httptest.Server
+	routes map[string]func(http.ResponseWriter, []byte)
+
+	data    sync.RWMutex // guards fixture and songs
+	fixture Fixture
+	songs   map[string]int // SongId -> index into fixture.Songs
+
+	lock    sync.Mutex
+	tokens  map[string]issuedToken
+	faults  map[string]*Fault
+	latency map[string]time.Duration
+	calls   map[string]int
+	nextTok int
+}
+
+type issuedToken struct {
+	appId   string
+	expires time.Time
+}
+
+// New starts a mock server on a loopback port. Call Close when done.
+func New(f Fixture) *Server {
+	s := NewUnstarted(f)
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// NewUnstarted returns a Server that is not listening, for mounting its
+// handler elsewhere. URL is empty and Close is a no-op.
+func NewUnstarted(f Fixture) *Server {
+	if f.TokenTTL == 0 {
+		f.TokenTTL = 900
+	}
+	s := &Server{
+		fixture: f,
+		songs:   make(map[string]int, len(f.Songs)),
+		tokens:  make(map[string]issuedToken),
+		faults:  make(map[string]*Fault),
+		latency: make(map[string]time.Duration),
+		calls:   make(map[string]int),
+	}
+	for i, song := range f.Songs {
+		s.songs[song.SongId] = i
+	}
+	s.routes = map[string]func(http.ResponseWriter, []byte){
+		PathGetSongList:            s.handleGetSongList,
+		PathGetSongInfo:            s.handleGetSongInfo,
+		PathGetSongUrl:             s.handleGetSongUrl,
+		PathSearchSong:             s.handleSearchSong,
+		PathQuerySongListPage:      s.pageHandler(func() []client.PlayListDetail { return s.fixture.Playlists }),
+		PathQuerySongListDetail:    s.detailHandler(func() []client.PlayListDetail { return s.fixture.Playlists }),
+		PathQueryRankingListPage:   s.pageHandler(func() []client.PlayListDetail { return s.fixture.Rankings }),
+		PathQueryRankingListDetail: s.detailHandler(func() []client.PlayListDetail { return s.fixture.Rankings }),
+	}
+	return s
+}
+
+func (s *Server) Close() {
+	if s.srv != nil {
+		s.srv.Close()
+	}
+}
+
+// InjectFault makes requests to path fail as described by f.
+func (s *Server) InjectFault(path string, f Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[path] = &f
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = make(map[string]*Fault)
+}
+
+// SetLatency delays every response on path by d. Use "" for all paths.
+func (s *Server) SetLatency(path string, d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latency[path] = d
+}
+
+// Calls returns how many requests reached path, including rejected ones.
+func (s *Server) Calls(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls[path]
+}
+
+// ExpireTokens invalidates every issued token, forcing clients to re-authenticate.
+func (s *Server) ExpireTokens() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tokens = make(map[string]issuedToken)
+}
+
+// PutSong adds a song to the catalog, or replaces the one with the same SongId.
+// Combine with Notify to simulate catalog updates and takedowns.
+func (s *Server) PutSong(song client.Song) {
+	s.data.Lock()
+	defer s.data.Unlock()
+	if i, ok := s.songs[song.SongId]; ok {
+		s.fixture.Songs[i] = song
+		return
+	}
+	s.songs[song.SongId] = len(s.fixture.Songs)
+	s.fixture.Songs = append(s.fixture.Songs, song)
+}
+
+// PutList adds or replaces a playlist (kind client.NotifyTypeSongList) or
+// ranking (client.NotifyTypeRankingList) by Code.
+func (s *Server) PutList(kind string, d client.PlayListDetail) {
+	s.data.Lock()
+	defer s.data.Unlock()
+	lists := &s.fixture.Playlists
+	if kind == client.NotifyTypeRankingList {
+		lists = &s.fixture.Rankings
+	}
+	for i := range *lists {
+		if (*lists)[i].Code == d.Code {
+			(*lists)[i] = d
+			return
+		}
+	}
+	*lists = append(*lists, d)
+}
+
+// Notify POSTs n to target as the platform's webhook would and returns the
+// receiver's acknowledgement.
+func (s *Server) Notify(target string, n client.Notification) (*client.NotificationResponse, error) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("notification target returned status %d", resp.StatusCode)
+	}
+	var ack client.NotificationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ack); err != nil {
+		return nil, fmt.Errorf("failed to parse notification response: %w", err)
+	}
+	return &ack, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	s.lock.Lock()
+	s.calls[path]++
+	delay := s.latency[""] + s.latency[path]
+	fault := s.faults[path]
+	var injected Fault
+	if fault != nil {
+		injected = *fault
+		if fault.Times > 0 {
+			if fault.Times--; fault.Times == 0 {
+				delete(s.faults, path)
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault != nil {
+		if injected.StatusCode != 0 {
+			w.WriteHeader(injected.StatusCode)
+			io.WriteString(w, injected.Body)
+			return
+		}
+		writeError(w, injected.Code, injected.Message)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, CodeBadRequest, "method not allowed")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, CodeBadRequest, "failed to read body")
+		return
+	}
+
+	if path == PathToken {
+		s.handleToken(w, body)
+		return
+	}
+
+	handler, ok := s.routes[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if code, msg := s.authenticate(r, body); code != 0 {
+		writeError(w, code, msg)
+		return
+	}
+
+	s.data.RLock()
+	defer s.data.RUnlock()
+	handler(w, body)
+}
+
+// authenticate checks the access token and recomputes the signature.
+func (s *Server) authenticate(r *http.Request, body []byte) (int, string) {
+	h := r.Header
+	appId := h.Get("appId")
+	token := h.Get("accessToken")
+
+	s.lock.Lock()
+	issued, ok := s.tokens[token]
+	s.lock.Unlock()
+	if !ok || issued.appId != appId || time.Now().After(issued.expires) {
+		return CodeInvalidToken, "invalid or expired accessToken"
+	}
+
+	params := client.SignParams{
+		AppId:       appId,
+		AccessToken: token,
+		Timestamp:   h.Get("timestamp"),
+		SignMethod:  h.Get("signMethod"),
+		TraceId:     h.Get("traceId"),
+		Source:      h.Get("source"),
+	}
+	signer, ok := client.LookupSigner(params.SignMethod, h.Get("signVersion"))
+	if !ok {
+		return CodeInvalidSign, "unsupported signMethod " + params.SignMethod
+	}
+	want := signer.Sign(params, body, r.URL.Path, r.URL.RawQuery, s.fixture.Apps[appId])
+	if subtle.ConstantTimeCompare([]byte(want), []byte(h.Get("sign"))) != 1 {
+		return CodeInvalidSign, "sign mismatch"
+	}
+	return 0, ""
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, body []byte) {
+	var req struct {
+		AppId     string `json:"appId"`
+		AppSecret string `json:"appSecret"`
+	}
+	json.Unmarshal(body, &req)
+	secret, ok := s.fixture.Apps[req.AppId]
+	if !ok || secret != req.AppSecret {
+		json.NewEncoder(w).Encode(client.TokenResponse{Code: strconv.Itoa(CodeInvalidToken), Message: "invalid appId or appSecret"})
+		return
+	}
+
+	s.lock.Lock()
+	s.nextTok++
+	token := fmt.Sprintf("mock-token-%d", s.nextTok)
+	s.tokens[token] = issuedToken{appId: req.AppId, expires: time.Now().Add(time.Duration(s.fixture.TokenTTL) * time.Second)}
+	s.lock.Unlock()
+
+	json.NewEncoder(w).Encode(client.TokenResponse{
+		Code:    "0",
+		Success: true,
+		Data:    client.TokenData{AccessToken: token, Expire: s.fixture.TokenTTL},
+	})
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client.BaseResponse{Code: 0, Success: true, Message: "success", Data: data})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client.BaseResponse{Code: code, Success: false, Message: msg, Msg: msg})
+}
+
+// Cursors encode the next offset so clients cannot rely on them being numbers.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(c string) (int, bool) {
+	if c == "" {
+		return 0, true
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil || !strings.HasPrefix(string(raw), "o:") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(raw[2:]))
+	return n, err == nil && n >= 0
+}
+
+func (s *Server) handleGetSongList(w http.ResponseWriter, body []byte) {
+	var req client.GetSongListRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, CodeBadRequest, "invalid body")
+		return
+	}
+	if req.QueryInfo == "END" {
+		writeError(w, CodeBadRequest, "cursor exhausted")
+		return
+	}
+	offset, ok := decodeCursor(req.QueryInfo)
+	if !ok {
+		writeError(w, CodeBadRequest, "invalid queryInfo")
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	songs := s.fixture.Songs
+	if req.SearchText != "" {
+		songs = nil
+		for _, song := range s.fixture.Songs {
+			if strings.Contains(song.SongName, req.SearchText) {
+				songs = append(songs, song)
+			}
+		}
+	}
+
+	resp := client.GetSongListResponse{NextQueryInfo: "END", SongList: []client.Song{}}
+	if offset < len(songs) {
+		end := min(offset+limit, len(songs))
+		resp.SongList = songs[offset:end]
+		if end < len(songs) {
+			resp.NextQueryInfo = encodeCursor(end)
+		}
+	}
+	writeData(w, resp)
+}
+
+func (s *Server) handleGetSongInfo(w http.ResponseWriter, body []byte) {
+	var req client.GetSongInfoRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.SongIdListStr == "" {
+		writeError(w, CodeBadRequest, "songIdListStr is required")
+		return
+	}
+	resp := client.GetSongInfoResponse{SongList: []client.Song{}}
+	for _, id := range strings.Split(req.SongIdListStr, ",") {
+		if i, ok := s.songs[strings.TrimSpace(id)]; ok {
+			resp.SongList = append(resp.SongList, s.fixture.Songs[i])
+		}
+	}
+	writeData(w, resp)
+}
+
+func (s *Server) handleGetSongUrl(w http.ResponseWriter, body []byte) {
+	var req client.GetSongUrlRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.SongId == "" {
+		writeError(w, CodeBadRequest, "songId is required")
+		return
+	}
+	i, ok := s.songs[req.SongId]
+	if !ok {
+		writeError(w, CodeNotFound, "song not found")
+		return
+	}
+	song := s.fixture.Songs[i]
+	if song.Status != 1 {
+		writeError(w, CodeNotFound, "song unavailable: "+song.TakeDownReason)
+		return
+	}
+
+	media, ok := s.fixture.Media[req.SongId]
+	if !ok {
+		media = []client.MediaInfo{{
+			FileType:    "mp3",
+			Complete:    1,
+			Url:         s.URL + "/media/" + req.SongId + ".mp3",
+			Expire:      time.Now().Add(time.Hour).Format("2006-01-02 15:04:05"),
+			StartSecond: "0",
+			EndSecond:   strconv.Itoa(song.Duration),
+		}}
+	}
+	writeData(w, client.GetSongUrlResponse{MediaList: media})
+}
+
+func (s *Server) handleSearchSong(w http.ResponseWriter, body []byte) {
+	var req client.SearchSongRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.SearchText == "" {
+		writeError(w, CodeBadRequest, "searchText is required")
+		return
+	}
+	if !client.ValidSearchType(req.SearchType) {
+		writeError(w, CodeBadRequest, "invalid searchType")
+		return
+	}
+	if req.Offset < 0 || req.Limit <= 0 {
+		writeError(w, CodeBadRequest, "invalid offset/limit")
+		return
+	}
+
+	text := strings.ToLower(req.SearchText)
+	filter, _ := req.Filter()
+	var hits []client.Song
+	for _, song := range s.fixture.Songs {
+		if !filter.Match(song.Status) {
+			continue
+		}
+		var fields []string
+		if req.SearchType != client.SearchTypeArtistName {
+			fields = append(fields, song.SongName)
+		}
+		if req.SearchType != client.SearchTypeSongName {
+			for _, a := range song.ArtistList {
+				fields = append(fields, a.ArtistName)
+			}
+		}
+		for _, f := range fields {
+			if strings.Contains(strings.ToLower(f), text) {
+				hits = append(hits, song)
+				break
+			}
+		}
+	}
+
+	resp := client.SearchSongResponse{Total: len(hits), SongList: []client.Song{}}
+	if req.Offset < len(hits) {
+		resp.SongList = hits[req.Offset:min(req.Offset+req.Limit, len(hits))]
+	}
+	writeData(w, resp)
+}
+
+func (s *Server) pageHandler(get func() []client.PlayListDetail) func(http.ResponseWriter, []byte) {
+	return func(w http.ResponseWriter, body []byte) {
+		lists := get()
+		var req client.PageRequest
+		if err := json.Unmarshal(body, &req); err != nil || req.Offset < 0 || req.Length <= 0 {
+			writeError(w, CodeBadRequest, "invalid offset/length")
+			return
+		}
+		resp := client.QuerySongListResponse{Total: len(lists), List: []client.PlayListInfo{}}
+		for i := req.Offset; i < len(lists) && i < req.Offset+req.Length; i++ {
+			d := lists[i]
+			resp.List = append(resp.List, client.PlayListInfo{
+				Code: d.Code, Title: d.Title, Description: d.Description,
+				Url: d.ImgUrl, ImgUrl: d.ImgUrl, Status: d.Status,
+			})
+		}
+		writeData(w, resp)
+	}
+}
+
+func (s *Server) detailHandler(get func() []client.PlayListDetail) func(http.ResponseWriter, []byte) {
+	return func(w http.ResponseWriter, body []byte) {
+		lists := get()
+		var req client.QuerySongListDetailRequest
+		if err := json.Unmarshal(body, &req); err != nil || req.Code == "" {
+			writeError(w, CodeBadRequest, "code is required")
+			return
+		}
+		for _, d := range lists {
+			if d.Code == req.Code {
+				writeData(w, d)
+				return
+			}
+		}
+		writeError(w, CodeNotFound, "list not found")
+	}
+}
@@ -0,0 +1,132 @@
+package mockserver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+	"github.com/leychan/yinsuda-music/pkg/mockserver"
+)
+
+func TestServer_Pagination(t *testing.T) {
+	srv := mockserver.New(mockserver.GenerateFixture(250, 1))
+	defer srv.Close()
+	c := client.NewClient(mockserver.TestAppId, mockserver.TestAppSecret, srv.URL)
+
+	seen := make(map[string]bool)
+	req := &client.GetSongListRequest{Limit: 100}
+	pages := 0
+	for {
+		resp, err := c.GetSongList(req)
+		if err != nil {
+			t.Fatalf("GetSongList failed: %v", err)
+		}
+		pages++
+		for _, s := range resp.SongList {
+			seen[s.SongId] = true
+		}
+		if resp.NextQueryInfo == "END" {
+			break
+		}
+		req = &client.GetSongListRequest{QueryInfo: resp.NextQueryInfo, Limit: 100}
+	}
+	if pages != 3 || len(seen) != 250 {
+		t.Errorf("expected 250 songs in 3 pages, got %d in %d", len(seen), pages)
+	}
+
+	search, err := c.SearchSong(&client.SearchSongRequest{SearchText: "song 1", SearchType: client.SearchTypeSongName, Offset: 5, Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchSong failed: %v", err)
+	}
+	if search.Total <= 15 || len(search.SongList) != 10 {
+		t.Errorf("unexpected search page: total %d, %d songs", search.Total, len(search.SongList))
+	}
+
+	if srv.Calls(mockserver.PathToken) != 1 {
+		t.Errorf("expected the token to be fetched once, got %d", srv.Calls(mockserver.PathToken))
+	}
+}
+
+func TestServer_RejectsBadSign(t *testing.T) {
+	srv := mockserver.New(mockserver.DefaultFixture())
+	defer srv.Close()
+
+	// Obtain a valid token through the client, then send a request signed with the wrong secret.
+	tp := client.NewTokenProvider(mockserver.TestAppId, mockserver.TestAppSecret, srv.URL+mockserver.PathToken, nil)
+	token, err := tp.GetAccessToken()
+	if err != nil {
+		t.Fatalf("GetAccessToken failed: %v", err)
+	}
+
+	body := `{"songIdListStr":"S001"}`
+	params := client.SignParams{AppId: mockserver.TestAppId, AccessToken: token, Timestamp: "20240101000000", SignMethod: "md5", TraceId: "t1"}
+	req, _ := http.NewRequest("POST", srv.URL+mockserver.PathGetSongInfo, strings.NewReader(body))
+	req.Header.Set("appId", params.AppId)
+	req.Header.Set("accessToken", token)
+	req.Header.Set("timestamp", params.Timestamp)
+	req.Header.Set("signMethod", "md5")
+	req.Header.Set("traceId", params.TraceId)
+	req.Header.Set("sign", client.CalculateSign(params, []byte(body), mockserver.PathGetSongInfo, nil, "wrong"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var base client.BaseResponse
+	json.NewDecoder(resp.Body).Decode(&base)
+	if base.Success || base.Code != mockserver.CodeInvalidSign {
+		t.Errorf("expected sign rejection, got %+v", base)
+	}
+}
+
+func TestServer_FaultsAndNotify(t *testing.T) {
+	srv := mockserver.New(mockserver.DefaultFixture())
+	defer srv.Close()
+	c := client.NewClient(mockserver.TestAppId, mockserver.TestAppSecret, srv.URL)
+
+	srv.InjectFault(mockserver.PathGetSongUrl, mockserver.Fault{StatusCode: 502, Body: "<html>bad gateway</html>", Times: 1})
+	_, err := c.GetSongUrl(&client.GetSongUrlRequest{SongId: "S001"})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 502 {
+		t.Fatalf("expected injected 502, got %v", err)
+	}
+	urls, err := c.GetSongUrl(&client.GetSongUrlRequest{SongId: "S001"})
+	if err != nil || len(urls.MediaList) != 1 {
+		t.Fatalf("fault should clear after one request: %v", err)
+	}
+
+	// Take a song down and push the notification to a receiver.
+	down := mockserver.DefaultFixture().Songs[0]
+	down.Status = 0
+	srv.PutSong(down)
+
+	var got client.Notification
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(client.NotificationResponse{Code: 0, Msg: "ok"})
+	}))
+	defer receiver.Close()
+
+	ack, err := srv.Notify(receiver.URL, client.Notification{
+		NotifyId:   "N1",
+		NotifyType: client.NotifyTypeSong,
+		AppId:      mockserver.TestAppId,
+		Songs:      []client.SongChange{{SongId: down.SongId}},
+	})
+	if err != nil || ack.Code != 0 {
+		t.Fatalf("Notify failed: %v %+v", err, ack)
+	}
+	if got.NotifyId != "N1" || len(got.Songs) != 1 {
+		t.Errorf("receiver got %+v", got)
+	}
+
+	info, err := c.GetSongInfo([]string{down.SongId})
+	if err != nil || info.SongList[0].Status != 0 {
+		t.Errorf("expected updated song, got %+v (%v)", info, err)
+	}
+}
@@ -0,0 +1,419 @@
+// Package search provides an in-process full-text index over catalog songs, so
+// devices can keep searching without a network round-trip to SearchSong.
+//
+// Song name, artist names, album name and language are indexed. Chinese text is
+// indexed as unigrams and bigrams, and is also searchable by full pinyin
+// ("zhuzai") and initial letters ("zzxl"). Latin words match exactly,
+// by prefix and, from four letters on, with small typos.
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/leychan/yinsuda-music/pkg/catalog"
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+type fieldMask uint8
+
+const (
+	fieldSong fieldMask = 1 << iota
+	fieldArtist
+	fieldAlbum
+	fieldLanguage
+)
+
+// Term key prefixes keep plain words and pinyin spellings in one sorted key space.
+const (
+	kindTerm     = "t:"
+	kindPinyin   = "p:"
+	kindInitials = "i:"
+)
+
+// defaultLimit applies when a request leaves Limit at 0.
+const defaultLimit = 20
+
+type doc struct {
+	song  client.Song
+	terms map[string]fieldMask
+}
+
+// Index is a searchable set of songs. It is safe for concurrent use.
+type Index struct {
+	pinyin PinyinTable
+
+	lock     sync.RWMutex
+	docs     map[string]*doc
+	postings map[string]map[string]fieldMask // term -> songId -> fields containing it
+	sorted   []string                        // all terms, rebuilt lazily for prefix scans
+	byLength map[int][]string                // plain and pinyin terms by rune length, for fuzzy matching
+	dirty    bool
+}
+
+// NewIndex creates an empty index that reads Han characters with pinyin. A
+// nil pinyin uses DefaultPinyinTable; an empty, non-nil table disables
+// pinyin and initial-letter matching.
+func NewIndex(pinyin PinyinTable) *Index {
+	if pinyin == nil {
+		pinyin = DefaultPinyinTable()
+	}
+	return &Index{
+		pinyin:   pinyin,
+		docs:     make(map[string]*doc),
+		postings: make(map[string]map[string]fieldMask),
+	}
+}
+
+// Add indexes songs, replacing any earlier version with the same SongId.
+func (ix *Index) Add(songs ...client.Song) {
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+	for _, song := range songs {
+		ix.removeLocked(song.SongId)
+
+		d := &doc{song: song, terms: make(map[string]fieldMask)}
+		ix.collect(d, song.SongName, fieldSong)
+		for _, a := range song.ArtistList {
+			ix.collect(d, a.ArtistName, fieldArtist)
+		}
+		ix.collect(d, song.Album.AlbumName, fieldAlbum)
+		ix.collect(d, song.Language, fieldLanguage)
+
+		for term, fields := range d.terms {
+			p := ix.postings[term]
+			if p == nil {
+				p = make(map[string]fieldMask)
+				ix.postings[term] = p
+				ix.dirty = true
+			}
+			p[song.SongId] = fields
+		}
+		ix.docs[song.SongId] = d
+	}
+}
+
+// Remove drops songs from the index.
+func (ix *Index) Remove(songIds ...string) {
+	ix.lock.Lock()
+	defer ix.lock.Unlock()
+	for _, id := range songIds {
+		ix.removeLocked(id)
+	}
+}
+
+// Len returns the number of indexed songs.
+func (ix *Index) Len() int {
+	ix.lock.RLock()
+	defer ix.lock.RUnlock()
+	return len(ix.docs)
+}
+
+// LoadStore indexes every song held by a catalog store.
+func (ix *Index) LoadStore(store catalog.Store) error {
+	var songs []client.Song
+	if err := store.Range(func(s client.Song) bool {
+		songs = append(songs, s)
+		return true
+	}); err != nil {
+		return err
+	}
+	ix.Add(songs...)
+	return nil
+}
+
+func (ix *Index) removeLocked(id string) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for term := range d.terms {
+		p := ix.postings[term]
+		delete(p, id)
+		if len(p) == 0 {
+			delete(ix.postings, term)
+			ix.dirty = true
+		}
+	}
+	delete(ix.docs, id)
+}
+
+func (ix *Index) collect(d *doc, text string, field fieldMask) {
+	for _, r := range splitRuns(text) {
+		if !r.han {
+			d.terms[kindTerm+r.text] |= field
+			continue
+		}
+		for _, t := range hanTerms(r.text) {
+			d.terms[kindTerm+t] |= field
+		}
+		// Index the spelling of every suffix so "xinli" finds 住在心里, not just "zhu...".
+		rs := []rune(r.text)
+		for i := range rs {
+			full, initials := ix.pinyin.pinyinForms(string(rs[i:]))
+			for _, p := range full {
+				d.terms[kindPinyin+p] |= field
+			}
+			for _, p := range initials {
+				d.terms[kindInitials+p] |= field
+			}
+		}
+	}
+}
+
+// Search runs req against the index and returns results shaped like the online API.
+// SearchType 0 is treated as full search. The status filter follows the wire
+// request: see SearchSongRequest.StatusFilter.
+func (ix *Index) Search(req *client.SearchSongRequest) (*client.SearchSongResponse, error) {
+	var fields fieldMask
+	switch req.SearchType {
+	case 0, client.SearchTypeFull:
+		fields = fieldSong | fieldArtist | fieldAlbum | fieldLanguage
+	case client.SearchTypeSongName:
+		fields = fieldSong
+	case client.SearchTypeArtistName:
+		fields = fieldArtist
+	default:
+		return nil, fmt.Errorf("invalid searchType %d", int(req.SearchType))
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		return nil, fmt.Errorf("invalid offset/limit %d/%d", req.Offset, req.Limit)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	runs := splitRuns(req.SearchText)
+	if len(runs) == 0 {
+		return &client.SearchSongResponse{}, nil
+	}
+
+	// The term lists are rebuilt after a change. The search then runs under
+	// the same write lock, so a concurrent Add cannot make them stale midway.
+	ix.lock.RLock()
+	if ix.dirty {
+		ix.lock.RUnlock()
+		ix.lock.Lock()
+		defer ix.lock.Unlock()
+		ix.rebuildLocked()
+	} else {
+		defer ix.lock.RUnlock()
+	}
+
+	// Every query run must match (AND); a song's score is the sum of its best match per run.
+	var scores map[string]int
+	for _, r := range runs {
+		hits := ix.matchRun(r, fields)
+		if scores == nil {
+			scores = hits
+			continue
+		}
+		for id, s := range scores {
+			if h, ok := hits[id]; ok {
+				scores[id] = s + h
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	type hit struct {
+		song  *client.Song
+		score int
+	}
+	filter, _ := req.Filter()
+	hits := make([]hit, 0, len(scores))
+	for id, score := range scores {
+		d := ix.docs[id]
+		if !filter.Match(d.song.Status) {
+			continue
+		}
+		hits = append(hits, hit{song: &d.song, score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		if hits[i].song.SongName != hits[j].song.SongName {
+			return hits[i].song.SongName < hits[j].song.SongName
+		}
+		return hits[i].song.SongId < hits[j].song.SongId
+	})
+
+	resp := &client.SearchSongResponse{Total: len(hits), SongList: []client.Song{}}
+	for i := req.Offset; i < len(hits) && i < req.Offset+limit; i++ {
+		resp.SongList = append(resp.SongList, *hits[i].song)
+	}
+	return resp, nil
+}
+
+// rebuildLocked refreshes sorted and byLength if terms were added or
+// removed. Callers hold the write lock.
+func (ix *Index) rebuildLocked() {
+	if !ix.dirty {
+		return
+	}
+	ix.sorted = ix.sorted[:0]
+	ix.byLength = make(map[int][]string)
+	for term := range ix.postings {
+		ix.sorted = append(ix.sorted, term)
+		for _, kind := range []string{kindTerm, kindPinyin} {
+			if strings.HasPrefix(term, kind) {
+				n := utf8.RuneCountInString(term) - len(kind)
+				ix.byLength[n] = append(ix.byLength[n], term)
+			}
+		}
+	}
+	sort.Strings(ix.sorted)
+	ix.dirty = false
+}
+
+// Match quality, multiplied by the weight of the field it was found in.
+const (
+	scoreExact  = 4
+	scorePrefix = 3
+	scorePinyin = 2
+	scoreFuzzy  = 1
+)
+
+func fieldWeight(f fieldMask) int {
+	switch {
+	case f&fieldSong != 0:
+		return 3
+	case f&fieldArtist != 0:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (ix *Index) matchRun(r run, fields fieldMask) map[string]int {
+	hits := make(map[string]int)
+	add := func(term string, quality int) {
+		for id, f := range ix.postings[term] {
+			if f &= fields; f == 0 {
+				continue
+			}
+			if s := quality * fieldWeight(f); s > hits[id] {
+				hits[id] = s
+			}
+		}
+	}
+
+	if r.han {
+		// Bigrams cover any substring of two or more characters.
+		terms := hanTerms(r.text)
+		if len(terms) > 1 {
+			bigrams := terms[:0:0]
+			for _, t := range terms {
+				if len([]rune(t)) == 2 {
+					bigrams = append(bigrams, t)
+				}
+			}
+			terms = bigrams
+		}
+		for i, t := range terms {
+			cur := make(map[string]int)
+			for id, f := range ix.postings[kindTerm+t] {
+				if f&fields != 0 {
+					cur[id] = scoreExact * fieldWeight(f&fields)
+				}
+			}
+			if i == 0 {
+				hits = cur
+				continue
+			}
+			for id := range hits {
+				if _, ok := cur[id]; !ok {
+					delete(hits, id)
+				}
+			}
+		}
+		return hits
+	}
+
+	q := r.text
+	ix.scanPrefix(kindTerm+q, func(term string) {
+		if term == kindTerm+q {
+			add(term, scoreExact)
+		} else {
+			add(term, scorePrefix)
+		}
+	})
+	ix.scanPrefix(kindPinyin+q, func(term string) { add(term, scorePinyin) })
+	ix.scanPrefix(kindInitials+q, func(term string) { add(term, scorePinyin) })
+
+	if edits := maxEdits(q); edits > 0 {
+		// Only terms within edits of the query's length can be close enough.
+		n := utf8.RuneCountInString(q)
+		for length := n - edits; length <= n+edits; length++ {
+			for _, term := range ix.byLength[length] {
+				kind := kindTerm
+				if strings.HasPrefix(term, kindPinyin) {
+					kind = kindPinyin
+				}
+				if withinDistance(q, term[len(kind):], edits) {
+					add(term, scoreFuzzy)
+				}
+			}
+		}
+	}
+	return hits
+}
+
+func (ix *Index) scanPrefix(prefix string, fn func(term string)) {
+	for i := sort.SearchStrings(ix.sorted, prefix); i < len(ix.sorted); i++ {
+		if !strings.HasPrefix(ix.sorted[i], prefix) {
+			return
+		}
+		fn(ix.sorted[i])
+	}
+}
+
+// maxEdits is the typo budget for a latin query word.
+func maxEdits(q string) int {
+	switch n := len([]rune(q)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// withinDistance reports whether the Levenshtein distance between a and b is at most max.
+func withinDistance(a, b string, max int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return false
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if rowMin > max {
+			return false
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)] <= max
+}
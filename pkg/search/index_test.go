@@ -0,0 +1,148 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+const testPinyin = `
+# subset of pinyin-data
+U+4F4F: zhù  # 住
+U+5728: zài  # 在
+U+5FC3: xīn  # 心
+U+91CC: lǐ  # 里
+U+5468: zhōu  # 周
+U+6770: jié  # 杰
+U+4F26: lún  # 伦
+U+6674: qíng  # 晴
+U+5929: tiān  # 天
+`
+
+func testIndex(t *testing.T) *Index {
+	table, err := LoadPinyinTable(strings.NewReader(testPinyin))
+	if err != nil {
+		t.Fatalf("LoadPinyinTable failed: %v", err)
+	}
+	ix := NewIndex(table)
+	ix.Add(
+		client.Song{SongId: "S1", SongName: "住在心里", Status: 1, Language: "国语",
+			ArtistList: []client.Artist{{ArtistName: "周杰伦"}}},
+		client.Song{SongId: "S2", SongName: "晴天", Status: 1,
+			ArtistList: []client.Artist{{ArtistName: "周杰伦"}}, Album: client.Album{AlbumName: "叶惠美"}},
+		client.Song{SongId: "S3", SongName: "Yesterday", Status: 0,
+			ArtistList: []client.Artist{{ArtistName: "The Beatles"}}},
+	)
+	return ix
+}
+
+func TestIndex_Search(t *testing.T) {
+	ix := testIndex(t)
+
+	cases := []struct {
+		name string
+		req  client.SearchSongRequest
+		want []string
+	}{
+		{"chinese substring", client.SearchSongRequest{SearchText: "心里"}, []string{"S1"}},
+		{"artist", client.SearchSongRequest{SearchText: "周杰伦", SearchType: client.SearchTypeArtistName}, []string{"S1", "S2"}},
+		{"artist not in song name", client.SearchSongRequest{SearchText: "周杰伦", SearchType: client.SearchTypeSongName}, nil},
+		{"full pinyin", client.SearchSongRequest{SearchText: "qingtian"}, []string{"S2"}},
+		{"pinyin mid-title", client.SearchSongRequest{SearchText: "xinli"}, []string{"S1"}},
+		{"initials", client.SearchSongRequest{SearchText: "zzxl"}, []string{"S1"}},
+		{"prefix", client.SearchSongRequest{SearchText: "yest"}, []string{"S3"}},
+		{"fuzzy", client.SearchSongRequest{SearchText: "yesterdy"}, []string{"S3"}},
+		{"fuzzy longer", client.SearchSongRequest{SearchText: "yesterdday"}, []string{"S3"}},
+		{"fuzzy too far", client.SearchSongRequest{SearchText: "yesteryear"}, nil},
+		{"multi word", client.SearchSongRequest{SearchText: "beatles yesterday"}, []string{"S3"}},
+		{"status filter", client.SearchSongRequest{SearchText: "yesterday", Status: 1}, nil},
+		{"full-width", client.SearchSongRequest{SearchText: "ＹＥＳＴＥＲＤＡＹ"}, []string{"S3"}},
+	}
+	for _, tc := range cases {
+		resp, err := ix.Search(&tc.req)
+		if err != nil {
+			t.Fatalf("%s: Search failed: %v", tc.name, err)
+		}
+		var got []string
+		for _, s := range resp.SongList {
+			got = append(got, s.SongId)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") || resp.Total != len(tc.want) {
+			t.Errorf("%s: expected %v, got %v (total %d)", tc.name, tc.want, got, resp.Total)
+		}
+	}
+}
+
+func TestIndex_PagingAndRemove(t *testing.T) {
+	ix := testIndex(t)
+
+	resp, _ := ix.Search(&client.SearchSongRequest{SearchText: "zhou", Offset: 1, Limit: 1})
+	if resp.Total != 2 || len(resp.SongList) != 1 {
+		t.Errorf("expected total 2 with one result on page, got %d/%d", resp.Total, len(resp.SongList))
+	}
+
+	ix.Remove("S2")
+	resp, _ = ix.Search(&client.SearchSongRequest{SearchText: "晴天"})
+	if resp.Total != 0 || ix.Len() != 2 {
+		t.Errorf("removed song still found: %+v", resp)
+	}
+
+	if _, err := ix.Search(&client.SearchSongRequest{SearchText: "x", SearchType: 9}); err == nil {
+		t.Errorf("expected error for invalid searchType")
+	}
+}
+
+// TestIndex_Concurrent adds and searches at once; run with -race. Every song
+// added before a search starts must be found by it.
+func TestIndex_Concurrent(t *testing.T) {
+	ix := testIndex(t)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				word := "track" + strconv.Itoa(w) + "x" + strconv.Itoa(i)
+				ix.Add(client.Song{SongId: word, SongName: word})
+				resp, err := ix.Search(&client.SearchSongRequest{SearchText: word})
+				// Similar words also match fuzzily; the exact one ranks first.
+				if err != nil || resp.Total == 0 || resp.SongList[0].SongId != word {
+					t.Errorf("%s: not found right after Add (%v)", word, err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if ix.Len() != 3+4*50 {
+		t.Errorf("Expected %d songs, got %d", 3+4*50, ix.Len())
+	}
+}
+
+func TestIndex_DefaultPinyin(t *testing.T) {
+	table := DefaultPinyinTable()
+	if len(table) != 6763 {
+		t.Errorf("Expected 6763 characters in the bundled table, got %d", len(table))
+	}
+	if got := table['重']; len(got) < 2 || got[0] != "zhong" || got[1] != "chong" {
+		t.Errorf("重: unexpected readings %v", got)
+	}
+
+	song := client.Song{SongId: "S1", SongName: "住在心里", Status: 1}
+	ix := NewIndex(nil)
+	ix.Add(song)
+	for _, q := range []string{"zhuzaixinli", "zzxl"} {
+		if res, err := ix.Search(&client.SearchSongRequest{SearchText: q}); err != nil || res.Total != 1 {
+			t.Errorf("%q: Expected a match with the bundled table, got %+v, %v", q, res, err)
+		}
+	}
+
+	off := NewIndex(PinyinTable{})
+	off.Add(song)
+	if res, err := off.Search(&client.SearchSongRequest{SearchText: "zzxl"}); err != nil || res.Total != 0 {
+		t.Errorf("Expected no pinyin matches with an empty table, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// PinyinTable maps a Han character to its toneless pinyin readings, most common first.
+type PinyinTable map[rune][]string
+
+//go:embed pinyin.txt
+var defaultPinyin string
+
+var (
+	defaultTableOnce sync.Once
+	defaultTable     PinyinTable
+)
+
+// DefaultPinyinTable returns the bundled table, which covers the 6763 Han
+// characters of GB2312. Load a fuller table, e.g. the complete pinyin-data
+// file, with LoadPinyinTable. The table is shared and must not be modified.
+func DefaultPinyinTable() PinyinTable {
+	defaultTableOnce.Do(func() {
+		t, err := LoadPinyinTable(strings.NewReader(defaultPinyin))
+		if err != nil {
+			panic("search: bundled pinyin table: " + err.Error())
+		}
+		defaultTable = t
+	})
+	return defaultTable
+}
+
+// LoadPinyinTable parses the pinyin-data text format, one character per line:
+//
+//	U+4E2D: zhōng,zhòng  # 中
+//
+// Tone marks are stripped; blank lines and '#' comments are skipped.
+func LoadPinyinTable(r io.Reader) (PinyinTable, error) {
+	table := make(PinyinTable)
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		code, readings, ok := strings.Cut(line, ":")
+		if !ok || !strings.HasPrefix(code, "U+") {
+			return nil, fmt.Errorf("pinyin table line %d: malformed %q", lineNo, line)
+		}
+		cp, err := strconv.ParseUint(strings.TrimPrefix(code, "U+"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("pinyin table line %d: %w", lineNo, err)
+		}
+
+		var list []string
+		for _, py := range strings.Split(readings, ",") {
+			py = stripTones(strings.TrimSpace(py))
+			if py != "" && !contains(list, py) {
+				list = append(list, py)
+			}
+		}
+		table[rune(cp)] = list
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+var toneless = map[rune]rune{
+	'ā': 'a', 'á': 'a', 'ǎ': 'a', 'à': 'a',
+	'ē': 'e', 'é': 'e', 'ě': 'e', 'è': 'e', 'ê': 'e',
+	'ī': 'i', 'í': 'i', 'ǐ': 'i', 'ì': 'i',
+	'ō': 'o', 'ó': 'o', 'ǒ': 'o', 'ò': 'o',
+	'ū': 'u', 'ú': 'u', 'ǔ': 'u', 'ù': 'u',
+	'ǖ': 'v', 'ǘ': 'v', 'ǚ': 'v', 'ǜ': 'v', 'ü': 'v',
+	'ń': 'n', 'ň': 'n', 'ǹ': 'n', 'ḿ': 'm',
+}
+
+func stripTones(s string) string {
+	return strings.Map(func(r rune) rune {
+		if plain, ok := toneless[r]; ok {
+			return plain
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// normalize lowercases s and folds full-width ASCII (common in CJK input) to half-width.
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		} else if r == 0x3000 {
+			r = ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// run is a maximal stretch of either Han characters or letters/digits.
+type run struct {
+	text string
+	han  bool
+}
+
+func splitRuns(s string) []run {
+	var runs []run
+	var cur []rune
+	curHan := false
+	flush := func() {
+		if len(cur) > 0 {
+			runs = append(runs, run{text: string(cur), han: curHan})
+			cur = cur[:0]
+		}
+	}
+	for _, r := range normalize(s) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if !curHan {
+				flush()
+			}
+			curHan = true
+			cur = append(cur, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if curHan {
+				flush()
+			}
+			curHan = false
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return runs
+}
+
+// hanTerms returns the unigrams and bigrams of a Han run. Bigram indexing is the
+// usual dictionary-free way to segment Chinese: any query substring of two or more
+// characters is covered by the AND of its bigrams.
+func hanTerms(s string) []string {
+	rs := []rune(s)
+	terms := make([]string, 0, 2*len(rs))
+	for i := range rs {
+		terms = append(terms, string(rs[i]))
+		if i+1 < len(rs) {
+			terms = append(terms, string(rs[i:i+2]))
+		}
+	}
+	return terms
+}
+
+// maxPinyinVariants caps the readings expanded for titles full of polyphonic characters.
+const maxPinyinVariants = 8
+
+// pinyinForms returns the full-pinyin and initial-letter spellings of a Han run,
+// e.g. 住在心里 -> ["zhuzaixinli"], ["zzxl"]. Characters missing from the table
+// break the run so that no spelling spans them.
+func (t PinyinTable) pinyinForms(s string) (full, initials []string) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	variants := []string{""}
+	inits := []string{""}
+	emit := func() {
+		for i := range variants {
+			if variants[i] != "" {
+				full = append(full, variants[i])
+				initials = append(initials, inits[i])
+			}
+		}
+		variants, inits = []string{""}, []string{""}
+	}
+
+	for _, r := range s {
+		readings := t[r]
+		if len(readings) == 0 {
+			emit()
+			continue
+		}
+		var nextV, nextI []string
+		for i, v := range variants {
+			for _, py := range readings {
+				if len(nextV) >= maxPinyinVariants {
+					break
+				}
+				nextV = append(nextV, v+py)
+				nextI = append(nextI, inits[i]+py[:1])
+			}
+		}
+		variants, inits = nextV, nextI
+	}
+	emit()
+	return full, initials
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package snapshot
+
+import (
+	"strconv"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Entry is a song at a 1-based position in a playlist or ranking.
+type Entry struct {
+	SongId   string `json:"songId"`
+	Position int    `json:"position"`
+}
+
+// Move is a song present in both versions at different positions.
+type Move struct {
+	SongId      string `json:"songId"`
+	OldPosition int    `json:"oldPosition"`
+	NewPosition int    `json:"newPosition"`
+}
+
+// FieldChange is a metadata change. Field is one of "title", "description", "imgUrl", "status".
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Diff describes how a PlayListDetail changed between two snapshots.
+type Diff struct {
+	Added   []Entry       `json:"added,omitempty"`   // positions in the new version
+	Removed []Entry       `json:"removed,omitempty"` // positions in the old version
+	Moved   []Move        `json:"moved,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 && len(d.Changes) == 0
+}
+
+// Compare diffs two versions of the same playlist or ranking.
+// If a song appears more than once, its first position is used.
+func Compare(old, new client.PlayListDetail) *Diff {
+	d := &Diff{}
+
+	field := func(name, o, n string) {
+		if o != n {
+			d.Changes = append(d.Changes, FieldChange{Field: name, Old: o, New: n})
+		}
+	}
+	field("title", old.Title, new.Title)
+	field("description", old.Description, new.Description)
+	field("imgUrl", old.ImgUrl, new.ImgUrl)
+	field("status", strconv.Itoa(old.Status), strconv.Itoa(new.Status))
+
+	oldPos := positions(old)
+	newPos := positions(new)
+
+	for i, s := range new.SongList {
+		op, inOld := oldPos[s.SongId]
+		np := newPos[s.SongId]
+		if np != i+1 {
+			continue // duplicate
+		}
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, Entry{SongId: s.SongId, Position: np})
+		case op != np:
+			d.Moved = append(d.Moved, Move{SongId: s.SongId, OldPosition: op, NewPosition: np})
+		}
+	}
+	for i, s := range old.SongList {
+		if oldPos[s.SongId] != i+1 {
+			continue
+		}
+		if _, ok := newPos[s.SongId]; !ok {
+			d.Removed = append(d.Removed, Entry{SongId: s.SongId, Position: i + 1})
+		}
+	}
+	return d
+}
+
+func positions(p client.PlayListDetail) map[string]int {
+	m := make(map[string]int, len(p.SongList))
+	for i, s := range p.SongList {
+		if _, ok := m[s.SongId]; !ok {
+			m[s.SongId] = i + 1
+		}
+	}
+	return m
+}
@@ -0,0 +1,87 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+func detail(title string, ids ...string) client.PlayListDetail {
+	d := client.PlayListDetail{Code: "R1", Title: title, Status: 1}
+	for _, id := range ids {
+		d.SongList = append(d.SongList, struct {
+			SongId string `json:"songId"`
+		}{id})
+	}
+	return d
+}
+
+type fakeSource struct {
+	current client.PlayListDetail
+}
+
+func (f *fakeSource) QuerySongListDetail(code string) (*client.QuerySongListDetailResponse, error) {
+	r := client.QuerySongListDetailResponse(f.current)
+	return &r, nil
+}
+
+func (f *fakeSource) QueryRankingListDetail(code string) (*client.QuerySongListDetailResponse, error) {
+	return f.QuerySongListDetail(code)
+}
+
+func TestCompare(t *testing.T) {
+	d := Compare(detail("Top", "A", "B", "C", "D"), detail("Top 10", "B", "A", "E", "D"))
+
+	if len(d.Added) != 1 || d.Added[0] != (Entry{SongId: "E", Position: 3}) {
+		t.Errorf("unexpected added: %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0] != (Entry{SongId: "C", Position: 3}) {
+		t.Errorf("unexpected removed: %+v", d.Removed)
+	}
+	want := []Move{{SongId: "B", OldPosition: 2, NewPosition: 1}, {SongId: "A", OldPosition: 1, NewPosition: 2}}
+	if len(d.Moved) != 2 || d.Moved[0] != want[0] || d.Moved[1] != want[1] {
+		t.Errorf("unexpected moved: %+v", d.Moved)
+	}
+	if len(d.Changes) != 1 || d.Changes[0] != (FieldChange{Field: "title", Old: "Top", New: "Top 10"}) {
+		t.Errorf("unexpected changes: %+v", d.Changes)
+	}
+}
+
+func TestTracker_Notification(t *testing.T) {
+	src := &fakeSource{current: detail("Top", "A", "B")}
+	store := NewDirStore(t.TempDir())
+	tr := NewTracker(src, store)
+
+	var reported int
+	tr.OnDiff = func(Target, *Diff) { reported++ }
+
+	n := &client.Notification{NotifyType: client.NotifyTypeRankingList, Codes: []string{"R1"}}
+	diffs, err := tr.HandleNotification(n)
+	if err != nil {
+		t.Fatalf("HandleNotification failed: %v", err)
+	}
+	if len(diffs["R1"].Added) != 2 {
+		t.Errorf("first snapshot should report all songs added: %+v", diffs["R1"])
+	}
+
+	// Unchanged: nothing saved, nothing reported.
+	if d, _ := tr.Refresh(Target{Kind: KindRankingList, Code: "R1"}); !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+
+	src.current = detail("Top", "B", "A")
+	if _, err := tr.HandleNotification(n); err != nil {
+		t.Fatalf("HandleNotification failed: %v", err)
+	}
+
+	hist, err := store.History(KindRankingList, "R1")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(hist) != 2 || reported != 2 {
+		t.Errorf("expected 2 snapshots and 2 reports, got %d/%d", len(hist), reported)
+	}
+	if hist[1].Detail.SongList[0].SongId != "B" {
+		t.Errorf("latest snapshot not last in history")
+	}
+}
@@ -0,0 +1,176 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// Kind distinguishes playlists from rankings. The values match the notification types.
+type Kind string
+
+const (
+	KindSongList    Kind = client.NotifyTypeSongList
+	KindRankingList Kind = client.NotifyTypeRankingList
+)
+
+// Snapshot is one captured version of a playlist or ranking.
+type Snapshot struct {
+	Kind    Kind                  `json:"kind"`
+	Code    string                `json:"code"`
+	TakenAt time.Time             `json:"takenAt"`
+	Detail  client.PlayListDetail `json:"detail"`
+}
+
+// Store keeps snapshot history per (kind, code).
+type Store interface {
+	Save(s Snapshot) error
+	// Latest returns the most recent snapshot, or ok=false if there is none.
+	Latest(kind Kind, code string) (s Snapshot, ok bool, err error)
+	// History returns all snapshots, oldest first.
+	History(kind Kind, code string) ([]Snapshot, error)
+}
+
+// MemoryStore is a non-persistent Store.
+type MemoryStore struct {
+	lock sync.RWMutex
+	hist map[string][]Snapshot
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hist: make(map[string][]Snapshot)}
+}
+
+func (m *MemoryStore) Save(s Snapshot) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := string(s.Kind) + "/" + s.Code
+	m.hist[key] = append(m.hist[key], s)
+	return nil
+}
+
+func (m *MemoryStore) Latest(kind Kind, code string) (Snapshot, bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	h := m.hist[string(kind)+"/"+code]
+	if len(h) == 0 {
+		return Snapshot{}, false, nil
+	}
+	return h[len(h)-1], true, nil
+}
+
+func (m *MemoryStore) History(kind Kind, code string) ([]Snapshot, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return append([]Snapshot(nil), m.hist[string(kind)+"/"+code]...), nil
+}
+
+// DirStore keeps one JSON file per snapshot under dir/<kind>/<code>/<unix-nanos>.json.
+type DirStore struct {
+	dir string
+}
+
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+func (d *DirStore) path(kind Kind, code string) string {
+	// Codes are opaque; escape them so they cannot traverse out of dir.
+	return filepath.Join(d.dir, string(kind), strings.NewReplacer("/", "%2F", "\\", "%5C", "..", "%2E%2E").Replace(code))
+}
+
+func (d *DirStore) Save(s Snapshot) error {
+	dir := d.path(s.Kind, s.Code)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(dir, strconv.FormatInt(s.TakenAt.UnixNano(), 10)+".json")
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return os.Rename(tmp, name)
+}
+
+func (d *DirStore) Latest(kind Kind, code string) (Snapshot, bool, error) {
+	names, err := d.list(kind, code)
+	if err != nil || len(names) == 0 {
+		return Snapshot{}, false, err
+	}
+	s, err := d.read(kind, code, names[len(names)-1])
+	return s, err == nil, err
+}
+
+func (d *DirStore) History(kind Kind, code string) ([]Snapshot, error) {
+	names, err := d.list(kind, code)
+	if err != nil {
+		return nil, err
+	}
+	hist := make([]Snapshot, 0, len(names))
+	for _, name := range names {
+		s, err := d.read(kind, code, name)
+		if err != nil {
+			return nil, err
+		}
+		hist = append(hist, s)
+	}
+	return hist, nil
+}
+
+// list returns snapshot file names sorted by capture time.
+func (d *DirStore) list(kind Kind, code string) ([]string, error) {
+	entries, err := os.ReadDir(d.path(kind, code))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type named struct {
+		name  string
+		nanos int64
+	}
+	var files []named
+	for _, e := range entries {
+		base, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(base, 10, 64); err == nil {
+			files = append(files, named{e.Name(), n})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].nanos < files[j].nanos })
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+func (d *DirStore) read(kind Kind, code, name string) (Snapshot, error) {
+	var s Snapshot
+	data, err := os.ReadFile(filepath.Join(d.path(kind, code), name))
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to parse snapshot %s: %w", name, err)
+	}
+	return s, nil
+}
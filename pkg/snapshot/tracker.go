@@ -0,0 +1,130 @@
+// Package snapshot records the history of playlists and rankings and reports
+// what changed between versions: songs added, removed and moved, plus
+// title/description/cover/status edits. Snapshots are refreshed on a schedule
+// (Tracker.Run) or when SONG_LIST/RANKING_LIST notifications arrive.
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leychan/yinsuda-music/pkg/client"
+)
+
+// DetailSource is the subset of *client.Client used by the Tracker.
+type DetailSource interface {
+	QuerySongListDetail(code string) (*client.QuerySongListDetailResponse, error)
+	QueryRankingListDetail(code string) (*client.QuerySongListDetailResponse, error)
+}
+
+// Target identifies one playlist or ranking to track.
+type Target struct {
+	Kind Kind
+	Code string
+}
+
+// Tracker fetches details, diffs them against the latest snapshot and saves new versions.
+type Tracker struct {
+	src   DetailSource
+	store Store
+
+	// OnDiff, if set, is called for every refresh that found changes.
+	// The first snapshot of a target reports all of its songs as added.
+	OnDiff func(t Target, d *Diff)
+}
+
+func NewTracker(src DetailSource, store Store) *Tracker {
+	return &Tracker{src: src, store: store}
+}
+
+// Refresh fetches the current detail of t and stores it if it differs from the
+// latest snapshot. The returned diff is empty when nothing changed.
+func (tr *Tracker) Refresh(t Target) (*Diff, error) {
+	var resp *client.QuerySongListDetailResponse
+	var err error
+	switch t.Kind {
+	case KindSongList:
+		resp, err = tr.src.QuerySongListDetail(t.Code)
+	case KindRankingList:
+		resp, err = tr.src.QueryRankingListDetail(t.Code)
+	default:
+		return nil, fmt.Errorf("unknown snapshot kind %q", t.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s %s: %w", t.Kind, t.Code, err)
+	}
+	current := client.PlayListDetail(*resp)
+
+	prev, ok, err := tr.store.Latest(t.Kind, t.Code)
+	if err != nil {
+		return nil, err
+	}
+	var d *Diff
+	if ok {
+		d = Compare(prev.Detail, current)
+	} else {
+		d = Compare(client.PlayListDetail{Title: current.Title, Description: current.Description,
+			ImgUrl: current.ImgUrl, Status: current.Status}, current)
+	}
+	if ok && d.Empty() {
+		return d, nil
+	}
+
+	err = tr.store.Save(Snapshot{Kind: t.Kind, Code: t.Code, TakenAt: time.Now(), Detail: current})
+	if err != nil {
+		return nil, err
+	}
+	if tr.OnDiff != nil && !d.Empty() {
+		tr.OnDiff(t, d)
+	}
+	return d, nil
+}
+
+// HandleNotification refreshes every code listed in a SONG_LIST or RANKING_LIST
+// notification. Other notification types are ignored. Refreshing continues past
+// failures; the first error is returned.
+func (tr *Tracker) HandleNotification(n *client.Notification) (map[string]*Diff, error) {
+	var kind Kind
+	switch n.NotifyType {
+	case client.NotifyTypeSongList:
+		kind = KindSongList
+	case client.NotifyTypeRankingList:
+		kind = KindRankingList
+	default:
+		return nil, nil
+	}
+
+	diffs := make(map[string]*Diff, len(n.Codes))
+	var firstErr error
+	for _, code := range n.Codes {
+		d, err := tr.Refresh(Target{Kind: kind, Code: code})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		diffs[code] = d
+	}
+	return diffs, firstErr
+}
+
+// Run refreshes targets immediately and then every interval until ctx is done.
+// Refresh errors are passed to onError (which may be nil) and do not stop the loop.
+func (tr *Tracker) Run(ctx context.Context, interval time.Duration, targets []Target, onError func(Target, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, t := range targets {
+			if _, err := tr.Refresh(t); err != nil && onError != nil {
+				onError(t, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}